@@ -0,0 +1,142 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ReadPreference selects the members of a replica set a read is sent to
+type ReadPreference string
+
+const (
+	ReadPrimary            ReadPreference = "primary"
+	ReadPrimaryPreferred   ReadPreference = "primaryPreferred"
+	ReadSecondary          ReadPreference = "secondary"
+	ReadSecondaryPreferred ReadPreference = "secondaryPreferred"
+	ReadNearest            ReadPreference = "nearest"
+)
+
+// ReadConcern sets the consistency and isolation of the data returned by a read
+type ReadConcern string
+
+const (
+	ReadConcernLocal        ReadConcern = "local"
+	ReadConcernAvailable    ReadConcern = "available"
+	ReadConcernMajority     ReadConcern = "majority"
+	ReadConcernLinearizable ReadConcern = "linearizable"
+	ReadConcernSnapshot     ReadConcern = "snapshot"
+)
+
+// WriteConcernMajority acknowledges a write once the majority of members applied it
+const WriteConcernMajority = "majority"
+
+// WriteOptions hold the consistency of Insert, Update, Upsert and Delete,
+// unset fields keep the defaults of the database
+type WriteOptions struct {
+	// WriteConcern is WriteConcernMajority, the number of members which must
+	// acknowledge the write or the name of a tag set
+	WriteConcern string
+	// Journal acknowledges the write once it is written to the on-disk
+	// journal, nil keeps the default of the database
+	Journal *bool
+	// WTimeout limits how long the write concern is waited for
+	WTimeout time.Duration
+}
+
+func CreateWriteOptions() *WriteOptions {
+	return &WriteOptions{}
+}
+
+// SetWriteConcern sets the members which must acknowledge the write,
+// WriteConcernMajority, a number such as "2" or the name of a tag set
+func (o *WriteOptions) SetWriteConcern(w string) *WriteOptions {
+	o.WriteConcern = w
+	return o
+}
+
+// SetMajority acknowledges the write once the majority of members applied it
+func (o *WriteOptions) SetMajority() *WriteOptions {
+	return o.SetWriteConcern(WriteConcernMajority)
+}
+
+func (o *WriteOptions) SetJournal(j bool) *WriteOptions {
+	o.Journal = &j
+	return o
+}
+
+// journaled reports whether the write waits for the on-disk journal
+func (o *WriteOptions) journaled() bool {
+	return o.Journal != nil && *o.Journal
+}
+
+func (o *WriteOptions) SetWTimeout(d time.Duration) *WriteOptions {
+	o.WTimeout = d
+	return o
+}
+
+// Validate returns an error matching ErrInvalidOptions when the write options
+// can not be satisfied
+func (o *WriteOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if n, err := strconv.Atoi(o.WriteConcern); err == nil {
+		if n < 0 {
+			return fmt.Errorf("%w: write concern must not be negative, got %d", ErrInvalidOptions, n)
+		}
+		if n == 0 && o.journaled() {
+			return fmt.Errorf("%w: journal requires acknowledged writes, write concern is 0", ErrInvalidOptions)
+		}
+	}
+	if o.WTimeout < 0 {
+		return fmt.Errorf("%w: write concern timeout must not be negative, got %v", ErrInvalidOptions, o.WTimeout)
+	}
+	return nil
+}
+
+// MergeWriteOptions combines the write options, the fields set by later
+// options override earlier ones. It returns nil when no options are given
+func MergeWriteOptions(opts ...*WriteOptions) *WriteOptions {
+	var merged *WriteOptions
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		if merged == nil {
+			merged = CreateWriteOptions()
+		}
+		if o.WriteConcern != "" {
+			merged.WriteConcern = o.WriteConcern
+		}
+		if o.Journal != nil {
+			merged.SetJournal(*o.Journal)
+		}
+		if o.WTimeout != 0 {
+			merged.WTimeout = o.WTimeout
+		}
+	}
+	return merged
+}
+
+// Validate returns an error matching ErrInvalidOptions when the read
+// preference or read concern is not known
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.ReadPreference {
+	case "", ReadPrimary, ReadPrimaryPreferred, ReadSecondary, ReadSecondaryPreferred, ReadNearest:
+	default:
+		return fmt.Errorf("%w: unknown read preference %q", ErrInvalidOptions, o.ReadPreference)
+	}
+	switch o.ReadConcern {
+	case "", ReadConcernLocal, ReadConcernAvailable, ReadConcernMajority, ReadConcernLinearizable, ReadConcernSnapshot:
+	default:
+		return fmt.Errorf("%w: unknown read concern %q", ErrInvalidOptions, o.ReadConcern)
+	}
+	if o.ReadConcern == ReadConcernLinearizable && o.ReadPreference != "" && o.ReadPreference != ReadPrimary {
+		return fmt.Errorf("%w: linearizable read concern requires the primary read preference", ErrInvalidOptions)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteOptions_Setters(t *testing.T) {
+	got := CreateWriteOptions().SetMajority().SetJournal(true).SetWTimeout(time.Second)
+	journal := true
+	want := &WriteOptions{WriteConcern: WriteConcernMajority, Journal: &journal, WTimeout: time.Second}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteOptions setters = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *WriteOptions
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"majority", CreateWriteOptions().SetMajority().SetJournal(true), false},
+		{"members", CreateWriteOptions().SetWriteConcern("2"), false},
+		{"tag_set", CreateWriteOptions().SetWriteConcern("multiRegion"), false},
+		{"unacknowledged", CreateWriteOptions().SetWriteConcern("0"), false},
+		{"negative", CreateWriteOptions().SetWriteConcern("-1"), true},
+		{"unacknowledged_journal", CreateWriteOptions().SetWriteConcern("0").SetJournal(true), true},
+		{"negative_timeout", CreateWriteOptions().SetWTimeout(-time.Second), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteOptions.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("WriteOptions.Validate() error = %v, want ErrInvalidOptions", err)
+			}
+		})
+	}
+}
+
+func TestMergeWriteOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []*WriteOptions
+		want *WriteOptions
+	}{
+		{"none", nil, nil},
+		{"nil", []*WriteOptions{nil}, nil},
+		{"one", []*WriteOptions{CreateWriteOptions().SetMajority()}, &WriteOptions{WriteConcern: "majority"}},
+		{"override", []*WriteOptions{
+			CreateWriteOptions().SetMajority().SetWTimeout(time.Second),
+			nil,
+			CreateWriteOptions().SetWriteConcern("2").SetJournal(true),
+		}, CreateWriteOptions().SetWriteConcern("2").SetJournal(true).SetWTimeout(time.Second)},
+		{"journal_off", []*WriteOptions{
+			CreateWriteOptions().SetMajority().SetJournal(true),
+			CreateWriteOptions().SetJournal(false),
+		}, CreateWriteOptions().SetMajority().SetJournal(false)},
+		{"journal_kept", []*WriteOptions{
+			CreateWriteOptions().SetJournal(true),
+			CreateWriteOptions().SetMajority(),
+		}, CreateWriteOptions().SetMajority().SetJournal(true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeWriteOptions(tt.opts...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeWriteOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *Options
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"empty", CreateOptions(), false},
+		{"secondary", CreateOptions().SetReadPreference(ReadSecondary).SetReadConcern(ReadConcernLocal), false},
+		{"linearizable", CreateOptions().SetReadPreference(ReadPrimary).SetReadConcern(ReadConcernLinearizable), false},
+		{"unknown_preference", CreateOptions().SetReadPreference("closest"), true},
+		{"unknown_concern", CreateOptions().SetReadConcern("strong"), true},
+		{"linearizable_secondary", CreateOptions().SetReadPreference(ReadNearest).SetReadConcern(ReadConcernLinearizable), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Options.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("Options.Validate() error = %v, want ErrInvalidOptions", err)
+			}
+		})
+	}
+}
@@ -6,14 +6,32 @@ import "context"
 type Database interface {
 	Open(ctx context.Context) error
 	Close(ctx context.Context) error
+	// Ping checks that the database can be reached
+	Ping(ctx context.Context) error
+	// Health pings the database and reports the latency and topology
+	Health(ctx context.Context) Health
 
-	Insert(collection string, object interface{}) error
+	// the write methods take optional WriteOptions, later options override earlier ones
+	Insert(collection string, object interface{}, opts ...*WriteOptions) error
 	FindOne(collection string, object interface{}, filter *Filter, opts *Options) error
 	FindAll(collection string, object interface{}, filter *Filter, opts *Options) error
-	Update(collection string, object interface{}, filter *Filter) error
-	Upsert(collection string, object interface{}, filter *Filter) error
-	Delete(collection string, filter *Filter) error
+	// Update replaces the first document matching filter with object. It returns
+	// an error matching ErrNotFound when no document matches, while updating a
+	// document to the values it already holds succeeds
+	Update(collection string, object interface{}, filter *Filter, opts ...*WriteOptions) error
+	// Upsert replaces the first document matching filter with object like
+	// Update, inserting object when no document matches
+	Upsert(collection string, object interface{}, filter *Filter, opts ...*WriteOptions) error
+	// Delete removes the first document matching filter, it returns an error
+	// matching ErrNotFound when no document matches
+	Delete(collection string, filter *Filter, opts ...*WriteOptions) error
 	Search(collection, search string, fields []string, slice interface{}) error
+	SearchWithOptions(collection, search string, fields []string, slice interface{}, filter *Filter, opts *Options) error
+
+	// Watch streams the changes made to documents of the collection which
+	// match the filter. Deletes hold no document so they are only matched on
+	// the _id of the filter, and always streamed when it has none
+	Watch(ctx context.Context, collection string, filter *Filter, opts *WatchOptions) (ChangeStream, error)
 }
 
 type Filter map[string]interface{}
@@ -22,6 +40,12 @@ type Options struct {
 	Limit int64
 	Skip  int64
 	Sort  *SortOption
+	// ScoreField is the field the relevance of a search result is written to,
+	// only used by SearchWithOptions
+	ScoreField string
+	// ReadPreference and ReadConcern override the defaults of the database for the read
+	ReadPreference ReadPreference
+	ReadConcern    ReadConcern
 }
 
 type SortOption struct {
@@ -54,3 +78,22 @@ func (o *Options) SetSort(key string, value int) *Options {
 	o.Sort = &SortOption{Key: key, Value: value}
 	return o
 }
+
+// SetScoreField sets the field the relevance score of each search result
+// is written to, search results are sorted by score unless a sort is set
+func (o *Options) SetScoreField(field string) *Options {
+	o.ScoreField = field
+	return o
+}
+
+// SetReadPreference sets the members of a replica set the read is sent to
+func (o *Options) SetReadPreference(p ReadPreference) *Options {
+	o.ReadPreference = p
+	return o
+}
+
+// SetReadConcern sets the consistency of the data returned by the read
+func (o *Options) SetReadConcern(c ReadConcern) *Options {
+	o.ReadConcern = c
+	return o
+}
@@ -86,3 +86,22 @@ func TestOptions_SetSort(t *testing.T) {
 		})
 	}
 }
+
+func TestOptions_SetScoreField(t *testing.T) {
+	tests := []struct {
+		name  string
+		o     *Options
+		field string
+		want  *Options
+	}{
+		{"score", CreateOptions(), "score", &Options{ScoreField: "score"}},
+		{"with_limit", CreateOptions().SetLimit(5), "relevance", &Options{Limit: 5, ScoreField: "relevance"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.o.SetScoreField(tt.field); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Options.SetScoreField() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the databases, they can be wrapped so compare them with errors.Is
+var (
+	// ErrTimeout is returned when an operation did not complete in time
+	ErrTimeout = errors.New("database operation timed out")
+	// ErrDuplicateKey is returned when a write violates a unique index
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrNetwork is returned when the database could not be reached
+	ErrNetwork = errors.New("database network error")
+	// ErrNotFound is returned when no document matches the filter of a write
+	ErrNotFound = errors.New("no documents matched the filter")
+	// ErrInvalidOptions is returned when the options of an operation are invalid
+	ErrInvalidOptions = errors.New("invalid options")
+)
+
+// ErrUnknownCollection matches every UnknownCollectionError with errors.Is
+var ErrUnknownCollection = errors.New("unknown collection")
+
+// UnknownCollectionError is returned when a collection does not exist,
+// or is not one of the collections the database is restricted to
+type UnknownCollectionError struct {
+	Collection string
+}
+
+func (e *UnknownCollectionError) Error() string {
+	return fmt.Sprintf("unknown collection %q", e.Collection)
+}
+
+func (e *UnknownCollectionError) Is(target error) bool {
+	return target == ErrUnknownCollection
+}
+
+// ErrClosed matches every ClosedError with errors.Is
+var ErrClosed = errors.New("database is closed")
+
+// ClosedError is returned when a database is used before Open or after Close
+type ClosedError struct {
+	Database string
+}
+
+func (e *ClosedError) Error() string {
+	return fmt.Sprintf("database %q is closed", e.Database)
+}
+
+func (e *ClosedError) Is(target error) bool {
+	return target == ErrClosed
+}
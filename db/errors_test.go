@@ -0,0 +1,35 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUnknownCollectionError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &UnknownCollectionError{Collection: "users"})
+	if !errors.Is(err, ErrUnknownCollection) {
+		t.Errorf("errors.Is(%v, ErrUnknownCollection) = false", err)
+	}
+	var unknown *UnknownCollectionError
+	if !errors.As(err, &unknown) || unknown.Collection != "users" {
+		t.Errorf("errors.As(%v) = %v", err, unknown)
+	}
+	if errors.Is(errors.New("unknown collection"), ErrUnknownCollection) {
+		t.Errorf("errors.Is() matched a different error")
+	}
+}
+
+func TestClosedError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &ClosedError{Database: "app"})
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("errors.Is(%v, ErrClosed) = false", err)
+	}
+	var closed *ClosedError
+	if !errors.As(err, &closed) || closed.Database != "app" {
+		t.Errorf("errors.As(%v) = %v", err, closed)
+	}
+	if errors.Is(err, ErrUnknownCollection) {
+		t.Errorf("errors.Is(%v, ErrUnknownCollection) = true", err)
+	}
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health is the state of the connection to a database, returned by Database.Health
+type Health struct {
+	Healthy bool
+	// Latency is the round trip time of the ping
+	Latency time.Duration
+	// Topology describes the deployment, such as "replica set rs0" or "standalone"
+	Topology string
+	// Servers are the addresses of the members of the deployment
+	Servers []string
+	// Err is the reason the database is unhealthy
+	Err       error
+	CheckedAt time.Time
+}
+
+// CheckHealth pings the database and returns its health with the latency of
+// the ping, topology and servers are left for the caller to fill in
+func CheckHealth(ctx context.Context, ping func(ctx context.Context) error) Health {
+	start := time.Now()
+	err := ping(ctx)
+	return Health{
+		Healthy:   err == nil,
+		Latency:   time.Since(start),
+		Err:       err,
+		CheckedAt: start,
+	}
+}
+
+// DefaultHealthTimeout limits the health check of a HealthHandler
+const DefaultHealthTimeout = 5 * time.Second
+
+// HealthHandler serves the health of a database as JSON for liveness and
+// readiness probes, responding with 503 Service Unavailable while it is unhealthy
+type HealthHandler struct {
+	database  Database
+	timeout   time.Duration
+	failAfter int
+
+	mu       sync.Mutex
+	failures int
+}
+
+// CreateHealthHandler creates a handler reporting the database unavailable
+// as soon as a health check fails, as a readiness probe should
+func CreateHealthHandler(database Database) *HealthHandler {
+	return &HealthHandler{database: database, timeout: DefaultHealthTimeout, failAfter: 1}
+}
+
+// SetTimeout limits how long a health check can take
+func (h *HealthHandler) SetTimeout(d time.Duration) *HealthHandler {
+	h.timeout = d
+	return h
+}
+
+// SetFailAfter only reports the database unavailable after n health checks
+// failed in a row, so a liveness probe does not restart a service on a blip
+func (h *HealthHandler) SetFailAfter(n int) *HealthHandler {
+	if n < 1 {
+		n = 1
+	}
+	h.failAfter = n
+	return h
+}
+
+// healthResponse is the JSON body written by HealthHandler
+type healthResponse struct {
+	Status    string    `json:"status"`
+	Latency   string    `json:"latency"`
+	Topology  string    `json:"topology,omitempty"`
+	Servers   []string  `json:"servers,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Failures  int       `json:"failures,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	health := h.database.Health(ctx)
+
+	h.mu.Lock()
+	if health.Healthy {
+		h.failures = 0
+	} else {
+		h.failures++
+	}
+	failures := h.failures
+	h.mu.Unlock()
+
+	res := healthResponse{
+		Status:    "up",
+		Latency:   health.Latency.String(),
+		Topology:  health.Topology,
+		Servers:   health.Servers,
+		Failures:  failures,
+		CheckedAt: health.CheckedAt,
+	}
+	status := http.StatusOK
+	if !health.Healthy {
+		res.Status = "degraded"
+		if failures >= h.failAfter {
+			res.Status = "down"
+			status = http.StatusServiceUnavailable
+		}
+		if health.Err != nil {
+			res.Error = health.Err.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_ = json.NewEncoder(w).Encode(res)
+	}
+}
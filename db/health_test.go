@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// healthDatabase reports the health it holds
+type healthDatabase struct {
+	Database
+	health Health
+}
+
+func (h *healthDatabase) Health(ctx context.Context) Health {
+	return h.health
+}
+
+func TestCheckHealth(t *testing.T) {
+	errDown := errors.New("down")
+	h := CheckHealth(context.Background(), func(ctx context.Context) error {
+		time.Sleep(time.Millisecond)
+		return errDown
+	})
+	if h.Healthy || h.Err != errDown || h.Latency < time.Millisecond || h.CheckedAt.IsZero() {
+		t.Errorf("CheckHealth() = %+v", h)
+	}
+	if h := CheckHealth(context.Background(), func(ctx context.Context) error { return nil }); !h.Healthy || h.Err != nil {
+		t.Errorf("CheckHealth() = %+v, want healthy", h)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	d := &healthDatabase{health: Health{Healthy: true, Latency: 2 * time.Millisecond, Topology: "replica set rs0", Servers: []string{"a:27017"}}}
+	readiness := CreateHealthHandler(d)
+	liveness := CreateHealthHandler(d).SetFailAfter(2)
+
+	serve := func(h http.Handler, method string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/health", nil))
+		var body map[string]interface{}
+		if method != http.MethodHead {
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("HealthHandler body %q error: %v", rec.Body.String(), err)
+			}
+		} else if rec.Body.Len() > 0 {
+			t.Errorf("HealthHandler wrote a body to HEAD: %q", rec.Body.String())
+		}
+		return rec.Code, body
+	}
+
+	code, body := serve(readiness, http.MethodGet)
+	if code != http.StatusOK || body["status"] != "up" || body["latency"] != "2ms" || body["topology"] != "replica set rs0" {
+		t.Errorf("HealthHandler healthy = %v %v", code, body)
+	}
+
+	d.health = Health{Err: errors.New("connection refused")}
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		wantCode   int
+		wantStatus string
+	}{
+		{"readiness", readiness, http.StatusServiceUnavailable, "down"},
+		{"liveness_first_failure", liveness, http.StatusOK, "degraded"},
+		{"liveness_second_failure", liveness, http.StatusServiceUnavailable, "down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := serve(tt.handler, http.MethodGet)
+			if code != tt.wantCode || body["status"] != tt.wantStatus || body["error"] != "connection refused" {
+				t.Errorf("HealthHandler = %v %v, want %v %v", code, body, tt.wantCode, tt.wantStatus)
+			}
+		})
+	}
+	if code, _ := serve(readiness, http.MethodHead); code != http.StatusServiceUnavailable {
+		t.Errorf("HealthHandler HEAD = %v, want %v", code, http.StatusServiceUnavailable)
+	}
+
+	// a healthy check resets the failures
+	d.health = Health{Healthy: true}
+	serve(liveness, http.MethodGet)
+	d.health = Health{Err: errors.New("timeout")}
+	if code, _ := serve(liveness, http.MethodGet); code != http.StatusOK {
+		t.Errorf("HealthHandler after recovery = %v, want %v", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Operation describes a call to a method of Database passing through middleware,
+// middleware can read and change it before calling the next handler
+type Operation struct {
+	// Method is the name of the Database method, such as "FindAll"
+	Method     string
+	Collection string
+	Filter     *Filter
+	Options    *Options
+	// WriteOptions are the write options of Insert, Update, Upsert and Delete merged into one
+	WriteOptions *WriteOptions
+	WatchOptions *WatchOptions
+	// Object is the object or slice passed to the method
+	Object interface{}
+	// Search and Fields are the arguments of Search and SearchWithOptions
+	Search string
+	Fields []string
+	// Context is the context passed to Open, Close, Ping, Health and Watch, the
+	// one set by WithContext for the other methods or context.Background()
+	Context context.Context
+
+	// Count is the number of documents found, inserted or upserted, set with
+	// HasCount once the database returned when it is known. Update leaves it
+	// unset as it does not tell whether the document changed, unless no
+	// document matched
+	Count    int
+	HasCount bool
+	// Stream is the change stream returned by Watch
+	Stream ChangeStream
+	// Health is the health returned by Health
+	Health Health
+}
+
+// IsWrite reports whether the operation changes documents
+func (op *Operation) IsWrite() bool {
+	switch op.Method {
+	case "Insert", "Update", "Upsert", "Delete":
+		return true
+	}
+	return false
+}
+
+// Handler executes an operation
+type Handler func(op *Operation) error
+
+// Middleware wraps the handler of the next middleware, or of the database
+type Middleware func(next Handler) Handler
+
+// Wrap returns a database passing every call through the middleware before the
+// base database. The first middleware is the outermost: it sees the operation
+// first and the result last
+func Wrap(base Database, mw ...Middleware) Database {
+	h := baseHandler(base)
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return &wrapped{handler: h, ctx: context.Background()}
+}
+
+// WithContext returns a copy of the database returned by Wrap whose methods
+// taking no context pass ctx to the middleware, so they can carry deadlines
+// and parent spans. Other databases are returned as they are
+func WithContext(d Database, ctx context.Context) Database {
+	w, ok := d.(*wrapped)
+	if !ok {
+		return d
+	}
+	c := *w
+	c.ctx = ctx
+	return &c
+}
+
+// baseHandler calls the method of the database described by the operation
+func baseHandler(d Database) Handler {
+	return func(op *Operation) error {
+		var err error
+		switch op.Method {
+		case "Open":
+			return d.Open(op.Context)
+		case "Close":
+			return d.Close(op.Context)
+		case "Ping":
+			return d.Ping(op.Context)
+		case "Health":
+			op.Health = d.Health(op.Context)
+			return op.Health.Err
+		case "Insert":
+			err = d.Insert(op.Collection, op.Object, op.WriteOptions)
+		case "FindOne":
+			err = d.FindOne(op.Collection, op.Object, op.Filter, op.Options)
+		case "FindAll":
+			err = d.FindAll(op.Collection, op.Object, op.Filter, op.Options)
+		case "Update":
+			err = d.Update(op.Collection, op.Object, op.Filter, op.WriteOptions)
+		case "Upsert":
+			err = d.Upsert(op.Collection, op.Object, op.Filter, op.WriteOptions)
+		case "Delete":
+			err = d.Delete(op.Collection, op.Filter, op.WriteOptions)
+		case "Search":
+			err = d.Search(op.Collection, op.Search, op.Fields, op.Object)
+		case "SearchWithOptions":
+			err = d.SearchWithOptions(op.Collection, op.Search, op.Fields, op.Object, op.Filter, op.Options)
+		case "Watch":
+			op.Stream, err = d.Watch(op.Context, op.Collection, op.Filter, op.WatchOptions)
+			return err
+		default:
+			return fmt.Errorf("db.Wrap() error: unknown method %q", op.Method)
+		}
+		op.Count, op.HasCount = resultCount(op, err)
+		return err
+	}
+}
+
+// resultCount returns the number of documents the operation found, inserted
+// or upserted and whether it is known
+func resultCount(op *Operation, err error) (int, bool) {
+	if errors.Is(err, ErrNotFound) {
+		return 0, true
+	}
+	if err != nil {
+		return 0, false
+	}
+	switch op.Method {
+	case "FindAll", "Search", "SearchWithOptions":
+		v := reflect.ValueOf(op.Object)
+		if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+			return v.Elem().Len(), true
+		}
+	case "FindOne", "Insert", "Upsert":
+		return 1, true
+	}
+	return 0, false
+}
+
+// wrapped implements Database by passing operations to the handler
+type wrapped struct {
+	handler Handler
+	ctx     context.Context
+}
+
+func (w *wrapped) do(op *Operation) error {
+	if op.Context == nil {
+		op.Context = w.ctx
+	}
+	return w.handler(op)
+}
+
+func (w *wrapped) Open(ctx context.Context) error {
+	return w.do(&Operation{Method: "Open", Context: ctx})
+}
+
+func (w *wrapped) Close(ctx context.Context) error {
+	return w.do(&Operation{Method: "Close", Context: ctx})
+}
+
+func (w *wrapped) Ping(ctx context.Context) error {
+	return w.do(&Operation{Method: "Ping", Context: ctx})
+}
+
+// Health returns the health set by the database, or an unhealthy health
+// holding the error of a middleware which did not call the database
+func (w *wrapped) Health(ctx context.Context) Health {
+	op := &Operation{Method: "Health", Context: ctx}
+	if err := w.do(op); err != nil && op.Health.Err == nil {
+		op.Health.Healthy = false
+		op.Health.Err = err
+	}
+	return op.Health
+}
+
+func (w *wrapped) Insert(collection string, object interface{}, opts ...*WriteOptions) error {
+	return w.do(&Operation{Method: "Insert", Collection: collection, Object: object, WriteOptions: MergeWriteOptions(opts...)})
+}
+
+func (w *wrapped) FindOne(collection string, object interface{}, filter *Filter, opts *Options) error {
+	return w.do(&Operation{Method: "FindOne", Collection: collection, Object: object, Filter: filter, Options: opts})
+}
+
+func (w *wrapped) FindAll(collection string, slice interface{}, filter *Filter, opts *Options) error {
+	return w.do(&Operation{Method: "FindAll", Collection: collection, Object: slice, Filter: filter, Options: opts})
+}
+
+func (w *wrapped) Update(collection string, object interface{}, filter *Filter, opts ...*WriteOptions) error {
+	return w.do(&Operation{Method: "Update", Collection: collection, Object: object, Filter: filter, WriteOptions: MergeWriteOptions(opts...)})
+}
+
+func (w *wrapped) Upsert(collection string, object interface{}, filter *Filter, opts ...*WriteOptions) error {
+	return w.do(&Operation{Method: "Upsert", Collection: collection, Object: object, Filter: filter, WriteOptions: MergeWriteOptions(opts...)})
+}
+
+func (w *wrapped) Delete(collection string, filter *Filter, opts ...*WriteOptions) error {
+	return w.do(&Operation{Method: "Delete", Collection: collection, Filter: filter, WriteOptions: MergeWriteOptions(opts...)})
+}
+
+func (w *wrapped) Search(collection, search string, fields []string, slice interface{}) error {
+	return w.do(&Operation{Method: "Search", Collection: collection, Search: search, Fields: fields, Object: slice})
+}
+
+func (w *wrapped) SearchWithOptions(collection, search string, fields []string, slice interface{}, filter *Filter, opts *Options) error {
+	return w.do(&Operation{Method: "SearchWithOptions", Collection: collection, Search: search, Fields: fields, Object: slice, Filter: filter, Options: opts})
+}
+
+func (w *wrapped) Watch(ctx context.Context, collection string, filter *Filter, opts *WatchOptions) (ChangeStream, error) {
+	op := &Operation{Method: "Watch", Context: ctx, Collection: collection, Filter: filter, WatchOptions: opts}
+	if err := w.do(op); err != nil {
+		return nil, err
+	}
+	return op.Stream, nil
+}
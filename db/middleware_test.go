@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// callDatabase records the method and arguments of every call
+type callDatabase struct {
+	calls []string
+	err   error
+}
+
+func (d *callDatabase) record(method string, args ...interface{}) error {
+	s := make([]string, len(args))
+	for i, arg := range args {
+		s[i] = reflect.ValueOf(arg).String()
+		if v := reflect.ValueOf(arg); v.Kind() == reflect.Ptr && !v.IsNil() {
+			s[i] = "*"
+		} else if v.Kind() == reflect.String {
+			s[i] = arg.(string)
+		}
+	}
+	d.calls = append(d.calls, method+"("+strings.Join(s, ",")+")")
+	return d.err
+}
+
+func (d *callDatabase) Open(ctx context.Context) error  { return d.record("Open") }
+func (d *callDatabase) Close(ctx context.Context) error { return d.record("Close") }
+func (d *callDatabase) Ping(ctx context.Context) error  { return d.record("Ping") }
+func (d *callDatabase) Health(ctx context.Context) Health {
+	err := d.record("Health")
+	return Health{Healthy: err == nil, Err: err, Topology: "fake"}
+}
+func (d *callDatabase) Insert(collection string, object interface{}, opts ...*WriteOptions) error {
+	return d.record("Insert", collection)
+}
+func (d *callDatabase) FindOne(collection string, object interface{}, filter *Filter, opts *Options) error {
+	return d.record("FindOne", collection, filter)
+}
+func (d *callDatabase) FindAll(collection string, slice interface{}, filter *Filter, opts *Options) error {
+	reflect.ValueOf(slice).Elem().Set(reflect.ValueOf([]string{"a", "b"}))
+	return d.record("FindAll", collection, filter)
+}
+func (d *callDatabase) Update(collection string, object interface{}, filter *Filter, opts ...*WriteOptions) error {
+	return d.record("Update", collection, filter)
+}
+func (d *callDatabase) Upsert(collection string, object interface{}, filter *Filter, opts ...*WriteOptions) error {
+	return d.record("Upsert", collection, filter)
+}
+func (d *callDatabase) Delete(collection string, filter *Filter, opts ...*WriteOptions) error {
+	if len(opts) != 1 || opts[0] == nil || opts[0].WriteConcern != WriteConcernMajority {
+		return errors.New("write options were not passed")
+	}
+	return d.record("Delete", collection, filter)
+}
+func (d *callDatabase) Search(collection, search string, fields []string, slice interface{}) error {
+	return d.record("Search", collection, search)
+}
+func (d *callDatabase) SearchWithOptions(collection, search string, fields []string, slice interface{}, filter *Filter, opts *Options) error {
+	return d.record("SearchWithOptions", collection, search, filter)
+}
+func (d *callDatabase) Watch(ctx context.Context, collection string, filter *Filter, opts *WatchOptions) (ChangeStream, error) {
+	return nil, d.record("Watch", collection)
+}
+
+func TestWrap(t *testing.T) {
+	base := &callDatabase{}
+	var trace []string
+	named := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(op *Operation) error {
+				trace = append(trace, name+" "+op.Method)
+				err := next(op)
+				trace = append(trace, name+" done")
+				return err
+			}
+		}
+	}
+	// tenancy restricts every filter to the tenant
+	tenancy := func(next Handler) Handler {
+		return func(op *Operation) error {
+			if op.Filter != nil {
+				f := Filter{"tenant": "acme"}
+				for k, v := range *op.Filter {
+					f[k] = v
+				}
+				op.Filter = &f
+			}
+			return next(op)
+		}
+	}
+	d := Wrap(base, named("outer"), named("inner"), tenancy)
+
+	var found []string
+	if err := d.FindAll("users", &found, &Filter{"name": "foo"}, nil); err != nil {
+		t.Fatal("FindAll() error:", err)
+	}
+	if want := []string{"outer FindAll", "inner FindAll", "inner done", "outer done"}; !reflect.DeepEqual(trace, want) {
+		t.Errorf("middleware order = %v, want %v", trace, want)
+	}
+	if want := []string{"FindAll(users,*)"}; !reflect.DeepEqual(base.calls, want) || len(found) != 2 {
+		t.Errorf("base calls = %v, found %v", base.calls, found)
+	}
+
+	ctx := context.Background()
+	calls := []func() error{
+		func() error { return d.Open(ctx) },
+		func() error { return d.Close(ctx) },
+		func() error { return d.Ping(ctx) },
+		func() error { return d.Health(ctx).Err },
+		func() error { return d.Insert("users", "doc") },
+		func() error { var s string; return d.FindOne("users", &s, nil, nil) },
+		func() error { return d.Update("users", "doc", &Filter{}) },
+		func() error { return d.Upsert("users", "doc", &Filter{}) },
+		func() error { return d.Delete("users", nil, CreateWriteOptions().SetMajority()) },
+		func() error { return d.Search("users", "foo", nil, &found) },
+		func() error { return d.SearchWithOptions("users", "foo", nil, &found, nil, nil) },
+		func() error { _, err := d.Watch(ctx, "users", nil, nil); return err },
+	}
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Errorf("error = %v", err)
+		}
+	}
+	if len(base.calls) != 13 {
+		t.Errorf("base calls = %v, want every method", base.calls)
+	}
+}
+
+func TestWrap_Operation(t *testing.T) {
+	base := &callDatabase{}
+	var ops []Operation
+	capture := func(next Handler) Handler {
+		return func(op *Operation) error {
+			err := next(op)
+			ops = append(ops, *op)
+			return err
+		}
+	}
+	d := Wrap(base, capture)
+
+	var found []string
+	_ = d.FindAll("users", &found, nil, CreateOptions().SetLimit(2))
+	_ = d.Update("users", "doc", &Filter{"_id": 1}, CreateWriteOptions().SetMajority(), CreateWriteOptions().SetJournal(true))
+	_ = d.Ping(context.Background())
+
+	if op := ops[0]; op.Method != "FindAll" || op.Collection != "users" || op.Options.Limit != 2 || op.Count != 2 || op.IsWrite() {
+		t.Errorf("FindAll operation = %+v", op)
+	}
+	if op := ops[1]; op.HasCount || !op.IsWrite() || !reflect.DeepEqual(op.WriteOptions, CreateWriteOptions().SetMajority().SetJournal(true)) {
+		t.Errorf("Update operation = %+v", op)
+	}
+	if op := ops[2]; op.Context == nil || op.HasCount {
+		t.Errorf("Ping operation = %+v", op)
+	}
+
+	// methods taking no context use the one set by WithContext
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "request")
+	var one string
+	_ = WithContext(d, ctx).FindOne("users", &one, nil, nil)
+	_ = d.FindOne("users", &one, nil, nil)
+	if got := ops[3].Context.Value(key{}); got != "request" {
+		t.Errorf("FindOne context value = %v, want the context of WithContext", got)
+	}
+	if got := ops[4].Context.Value(key{}); got != nil {
+		t.Errorf("FindOne context value = %v, WithContext changed the database", got)
+	}
+	if got := WithContext(base, ctx); got != Database(base) {
+		t.Errorf("WithContext() = %v, want the database not returned by Wrap", got)
+	}
+
+	// errors of the database pass through middleware
+	base.err = ErrNetwork
+	if err := d.Insert("users", "doc"); !errors.Is(err, ErrNetwork) {
+		t.Errorf("Insert() error = %v, want ErrNetwork", err)
+	}
+	if h := d.Health(context.Background()); h.Healthy || h.Topology != "fake" {
+		t.Errorf("Health() = %+v, want the unhealthy health of the database", h)
+	}
+	if op := ops[len(ops)-1]; op.HasCount {
+		t.Errorf("failed operation count = %d, want unset", op.Count)
+	}
+	base.err = ErrNotFound
+	_ = d.Update("users", "doc", &Filter{"_id": 2})
+	if op := ops[len(ops)-1]; !op.HasCount || op.Count != 0 {
+		t.Errorf("not found operation count = %d, %v, want 0", op.Count, op.HasCount)
+	}
+	base.err = ErrNetwork
+
+	// middleware can stop operations
+	deny := func(next Handler) Handler {
+		return func(op *Operation) error {
+			if op.IsWrite() {
+				return errors.New("read only")
+			}
+			return next(op)
+		}
+	}
+	base.err = nil
+	base.calls = nil
+	d = Wrap(base, deny)
+	if err := d.Delete("users", &Filter{}); err == nil || len(base.calls) != 0 {
+		t.Errorf("Delete() error = %v, calls %v, want denied", err, base.calls)
+	}
+	d = Wrap(base, func(next Handler) Handler {
+		return func(op *Operation) error { return ErrTimeout }
+	})
+	if h := d.Health(context.Background()); h.Healthy || !errors.Is(h.Err, ErrTimeout) {
+		t.Errorf("Health() = %+v, want the middleware error", h)
+	}
+}
@@ -1,8 +1,15 @@
 package db
 
 import (
+	"reflect"
+	"strconv"
+	"strings"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 // convertToMongoFilter converts database.Filter to a bson.M document
@@ -13,6 +20,67 @@ func ConvertToMongoFilter(filter *Filter) bson.M {
 	return bson.M(*filter)
 }
 
+// ConvertToWatchPipeline converts database.Filter to a change stream pipeline
+// matching the full document of inserts, updates and replaces. Deletes hold no
+// full document, so they are matched on the _id of the filter against their
+// document key, and every delete is matched when the filter has no _id
+func ConvertToWatchPipeline(filter *Filter) bson.A {
+	if filter == nil || len(*filter) == 0 {
+		return bson.A{}
+	}
+	deletes := bson.M{"operationType": OperationDelete}
+	if id, ok := (*filter)["_id"]; ok {
+		deletes["documentKey._id"] = id
+	}
+	return bson.A{bson.M{"$match": bson.M{"$or": bson.A{
+		prefixFields(*filter, "fullDocument."),
+		deletes,
+	}}}}
+}
+
+// prefixFields returns a copy of the filter with its field paths prefixed,
+// the filters within the logical operators $and, $or and $nor are prefixed too
+func prefixFields(filter map[string]interface{}, prefix string) bson.M {
+	m := bson.M{}
+	for k, v := range filter {
+		switch k {
+		case "$and", "$or", "$nor":
+			m[k] = prefixFilters(v, prefix)
+		default:
+			if strings.HasPrefix(k, "$") {
+				m[k] = v
+			} else {
+				m[prefix+k] = v
+			}
+		}
+	}
+	return m
+}
+
+// prefixFilters prefixes the filters of the array of a logical operator
+func prefixFilters(v interface{}, prefix string) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return v
+	}
+	filters := make(bson.A, rv.Len())
+	for i := range filters {
+		switch f := rv.Index(i).Interface().(type) {
+		case Filter:
+			filters[i] = prefixFields(f, prefix)
+		case bson.M:
+			filters[i] = prefixFields(f, prefix)
+		case map[string]interface{}:
+			filters[i] = prefixFields(f, prefix)
+		case bson.D:
+			filters[i] = prefixFields(f.Map(), prefix)
+		default:
+			filters[i] = f
+		}
+	}
+	return filters
+}
+
 // convertToFindOptions converts database.Options to options.FindOptions
 func ConvertToFindOptions(opts *Options) *options.FindOptions {
 	if opts == nil {
@@ -31,6 +99,45 @@ func ConvertToFindOptions(opts *Options) *options.FindOptions {
 	return o
 }
 
+// ConvertToSearchFilter converts database.Filter to a bson.M document
+// containing the $text search, the filter itself is left unchanged
+func ConvertToSearchFilter(search string, filter *Filter) bson.M {
+	f := bson.M{}
+	if filter != nil {
+		for k, v := range *filter {
+			f[k] = v
+		}
+	}
+	f["$text"] = bson.M{"$search": search}
+	return f
+}
+
+// DefaultScoreField is the field the text score is projected into when
+// results are sorted by score without a ScoreField
+const DefaultScoreField = "score"
+
+// ConvertToSearchOptions converts database.Options to options.FindOptions for a
+// $text search. The text score is projected into the score field and results
+// are sorted by score unless another sort is given. MongoDB before 4.4 requires
+// the projection of the score it sorts by so it is projected into
+// DefaultScoreField when no score field is set
+func ConvertToSearchOptions(opts *Options) *options.FindOptions {
+	o := ConvertToFindOptions(opts)
+	scoreField := DefaultScoreField
+	if opts != nil && opts.ScoreField != "" {
+		scoreField = opts.ScoreField
+	}
+	score := bson.M{scoreField: bson.M{"$meta": "textScore"}}
+	sortByScore := opts == nil || opts.Sort == nil
+	if sortByScore || opts.ScoreField != "" {
+		o.SetProjection(score)
+	}
+	if sortByScore {
+		o.SetSort(score)
+	}
+	return o
+}
+
 // convertToMongoOne converts database.Options to options.FindOneOptions
 func ConvertToFindOneOptions(opts *Options) *options.FindOneOptions {
 	if opts == nil {
@@ -45,3 +152,58 @@ func ConvertToFindOneOptions(opts *Options) *options.FindOneOptions {
 	}
 	return o
 }
+
+// ConvertToReadCollectionOptions converts the read preference and read concern of
+// database.Options to options.CollectionOptions, nil when neither is set
+func ConvertToReadCollectionOptions(opts *Options) (*options.CollectionOptions, error) {
+	if opts == nil || (opts.ReadPreference == "" && opts.ReadConcern == "") {
+		return nil, nil
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	o := options.Collection()
+	if opts.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(string(opts.ReadPreference))
+		if err != nil {
+			return nil, err
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, err
+		}
+		o.SetReadPreference(rp)
+	}
+	if opts.ReadConcern != "" {
+		o.SetReadConcern(readconcern.New(readconcern.Level(string(opts.ReadConcern))))
+	}
+	return o, nil
+}
+
+// ConvertToWriteCollectionOptions converts database.WriteOptions to
+// options.CollectionOptions setting the write concern, nil when none is set
+func ConvertToWriteCollectionOptions(opts *WriteOptions) (*options.CollectionOptions, error) {
+	if opts == nil || (opts.WriteConcern == "" && opts.Journal == nil && opts.WTimeout == 0) {
+		return nil, nil
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	var wc []writeconcern.Option
+	switch n, err := strconv.Atoi(opts.WriteConcern); {
+	case opts.WriteConcern == "":
+	case opts.WriteConcern == WriteConcernMajority:
+		wc = append(wc, writeconcern.WMajority())
+	case err == nil:
+		wc = append(wc, writeconcern.W(n))
+	default:
+		wc = append(wc, writeconcern.WTagSet(opts.WriteConcern))
+	}
+	if opts.Journal != nil {
+		wc = append(wc, writeconcern.J(*opts.Journal))
+	}
+	if opts.WTimeout > 0 {
+		wc = append(wc, writeconcern.WTimeout(opts.WTimeout))
+	}
+	return options.Collection().SetWriteConcern(writeconcern.New(wc...)), nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 func TestConvertToMongoFilter(t *testing.T) {
@@ -76,3 +77,166 @@ func TestConvertToFindOneOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertToSearchFilter(t *testing.T) {
+	filter := &Filter{"foo": "bar"}
+	tests := []struct {
+		name   string
+		search string
+		filter *Filter
+		want   bson.M
+	}{
+		{"nil", "term", nil, bson.M{"$text": bson.M{"$search": "term"}}},
+		{"filter", "term", filter, bson.M{"foo": "bar", "$text": bson.M{"$search": "term"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertToSearchFilter(tt.search, tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConvertToSearchFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, ok := (*filter)["$text"]; ok {
+		t.Errorf("ConvertToSearchFilter() modified the filter: %v", *filter)
+	}
+}
+
+func TestConvertToSearchOptions(t *testing.T) {
+	scoreSort := func(field string) bson.M {
+		return bson.M{field: bson.M{"$meta": "textScore"}}
+	}
+	tests := []struct {
+		name string
+		opts *Options
+		want *options.FindOptions
+	}{
+		{"nil", nil, options.Find().SetProjection(scoreSort("score")).SetSort(scoreSort("score"))},
+		{"limit_skip", CreateOptions().SetLimit(5).SetSkip(10),
+			options.Find().SetLimit(5).SetSkip(10).SetProjection(scoreSort("score")).SetSort(scoreSort("score"))},
+		{"sort_without_score", CreateOptions().SetSort("date", 1), options.Find().SetSort(bson.M{"date": 1})},
+		{"default_score_field", CreateOptions().SetSort("date", 1).SetScoreField("score"),
+			options.Find().SetSort(bson.M{"date": 1}).SetProjection(scoreSort("score"))},
+		{"score_field", CreateOptions().SetScoreField("relevance"),
+			options.Find().SetProjection(scoreSort("relevance")).SetSort(scoreSort("relevance"))},
+		{"sort", CreateOptions().SetSort("date", -1).SetScoreField("relevance"),
+			options.Find().SetSort(bson.M{"date": -1}).SetProjection(scoreSort("relevance"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertToSearchOptions(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConvertToSearchOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertToWatchPipeline(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   bson.A
+	}{
+		{"nil", nil, bson.A{}},
+		{"empty", &Filter{}, bson.A{}},
+		{"filter", &Filter{"foo": "bar"}, bson.A{bson.M{"$match": bson.M{"$or": bson.A{
+			bson.M{"fullDocument.foo": "bar"},
+			bson.M{"operationType": OperationDelete},
+		}}}}},
+		{"id", &Filter{"_id": "1", "foo": "bar"}, bson.A{bson.M{"$match": bson.M{"$or": bson.A{
+			bson.M{"fullDocument._id": "1", "fullDocument.foo": "bar"},
+			bson.M{"operationType": OperationDelete, "documentKey._id": "1"},
+		}}}}},
+		{"operators", &Filter{
+			"$or":   []interface{}{Filter{"foo": "bar"}, bson.M{"$and": bson.A{bson.M{"n": bson.M{"$gt": 1}}}}},
+			"$nor":  bson.A{bson.D{{Key: "baz", Value: true}}},
+			"$expr": bson.M{"$eq": bson.A{"$a", "$b"}},
+		}, bson.A{bson.M{"$match": bson.M{"$or": bson.A{
+			bson.M{
+				"$or":   bson.A{bson.M{"fullDocument.foo": "bar"}, bson.M{"$and": bson.A{bson.M{"fullDocument.n": bson.M{"$gt": 1}}}}},
+				"$nor":  bson.A{bson.M{"fullDocument.baz": true}},
+				"$expr": bson.M{"$eq": bson.A{"$a", "$b"}},
+			},
+			bson.M{"operationType": OperationDelete},
+		}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertToWatchPipeline(tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConvertToWatchPipeline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertToReadCollectionOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *Options
+		wantNil  bool
+		wantMode readpref.Mode
+		wantRC   string
+		wantErr  bool
+	}{
+		{"nil", nil, true, 0, "", false},
+		{"unset", CreateOptions().SetLimit(1), true, 0, "", false},
+		{"secondary", CreateOptions().SetReadPreference(ReadSecondaryPreferred), false, readpref.SecondaryPreferredMode, "", false},
+		{"majority", CreateOptions().SetReadConcern(ReadConcernMajority), false, 0, "majority", false},
+		{"invalid", CreateOptions().SetReadPreference("closest"), true, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertToReadCollectionOptions(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertToReadCollectionOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("ConvertToReadCollectionOptions() = %v, want nil %v", got, tt.wantNil)
+			}
+			if got == nil {
+				return
+			}
+			if tt.wantMode != 0 && (got.ReadPreference == nil || got.ReadPreference.Mode() != tt.wantMode) {
+				t.Errorf("ConvertToReadCollectionOptions() read preference = %v, want %v", got.ReadPreference, tt.wantMode)
+			}
+			if tt.wantRC != "" && (got.ReadConcern == nil || got.ReadConcern.GetLevel() != tt.wantRC) {
+				t.Errorf("ConvertToReadCollectionOptions() read concern = %v, want %v", got.ReadConcern, tt.wantRC)
+			}
+		})
+	}
+}
+
+func TestConvertToWriteCollectionOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *WriteOptions
+		wantW   interface{}
+		wantJ   bool
+		wantNil bool
+		wantErr bool
+	}{
+		{"nil", nil, nil, false, true, false},
+		{"unset", CreateWriteOptions(), nil, false, true, false},
+		{"majority", CreateWriteOptions().SetMajority().SetJournal(true), "majority", true, false, false},
+		{"members", CreateWriteOptions().SetWriteConcern("2"), 2, false, false, false},
+		{"journal_off", CreateWriteOptions().SetJournal(false), nil, false, false, false},
+		{"tag_set", CreateWriteOptions().SetWriteConcern("dc"), "dc", false, false, false},
+		{"invalid", CreateWriteOptions().SetWriteConcern("-1"), nil, false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertToWriteCollectionOptions(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertToWriteCollectionOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("ConvertToWriteCollectionOptions() = %v, want nil %v", got, tt.wantNil)
+			}
+			if got == nil {
+				return
+			}
+			if w := got.WriteConcern.GetW(); w != tt.wantW || got.WriteConcern.GetJ() != tt.wantJ {
+				t.Errorf("ConvertToWriteCollectionOptions() = w: %v j: %v, want w: %v j: %v", w, got.WriteConcern.GetJ(), tt.wantW, tt.wantJ)
+			}
+		})
+	}
+}
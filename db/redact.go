@@ -0,0 +1,125 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Redacted replaces the values of sensitive fields
+const Redacted = "[REDACTED]"
+
+// DefaultRedactedFields are the fields a Redactor hides unless set otherwise
+var DefaultRedactedFields = []string{"password", "secret", "token", "apiKey"}
+
+// Redactor hides sensitive values of filters before they are logged or traced
+type Redactor struct {
+	fields map[string]bool
+	values bool
+}
+
+// CreateRedactor creates a redactor hiding DefaultRedactedFields
+func CreateRedactor() *Redactor {
+	return (&Redactor{}).SetFields(DefaultRedactedFields...)
+}
+
+// SetFields sets the fields which are redacted, matched without case against
+// the last part of dotted keys so "password" redacts "user.password"
+func (r *Redactor) SetFields(fields ...string) *Redactor {
+	r.fields = make(map[string]bool, len(fields))
+	return r.AddFields(fields...)
+}
+
+// AddFields redacts the fields in addition to the ones already set
+func (r *Redactor) AddFields(fields ...string) *Redactor {
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = true
+	}
+	return r
+}
+
+// SetRedactValues replaces every value with "?", keeping only the shape of
+// the filter: its fields and operators
+func (r *Redactor) SetRedactValues(all bool) *Redactor {
+	r.values = all
+	return r
+}
+
+// sensitive reports whether the values of the key are redacted
+func (r *Redactor) sensitive(key string) bool {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return r.fields[strings.ToLower(key)]
+}
+
+// Filter returns a copy of the filter with the sensitive values redacted, nil
+// when the filter is nil
+func (r *Redactor) Filter(filter *Filter) map[string]interface{} {
+	if filter == nil {
+		return nil
+	}
+	return r.document(*filter)
+}
+
+func (r *Redactor) document(doc map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		m[k] = r.Value(k, v)
+	}
+	return m
+}
+
+// Value returns the value of the key with the sensitive parts redacted,
+// documents and arrays are redacted recursively
+func (r *Redactor) Value(key string, value interface{}) interface{} {
+	if r.sensitive(key) {
+		return Redacted
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case Filter:
+		return r.document(v)
+	case map[string]interface{}:
+		return r.document(v)
+	case primitive.M:
+		return r.document(v)
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			m[e.Key] = r.Value(e.Key, e.Value)
+		}
+		return m
+	case primitive.E:
+		return map[string]interface{}{v.Key: r.Value(v.Key, v.Value)}
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			m := make(map[string]interface{}, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				k := iter.Key().String()
+				m[k] = r.Value(k, iter.Value().Interface())
+			}
+			return m
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break // binary data and ids such as primitive.ObjectID
+		}
+		a := make([]interface{}, rv.Len())
+		for i := range a {
+			a[i] = r.Value(key, rv.Index(i).Interface())
+		}
+		return a
+	}
+	if r.values {
+		return "?"
+	}
+	return value
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRedactor_Filter(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("5fa1c2d3e4f5a6b7c8d9e0f1")
+	filter := &Filter{
+		"_id":           id,
+		"owners":        bson.M{"$in": []primitive.ObjectID{id}},
+		"name":          "foo",
+		"Password":      "hunter2",
+		"user.apikey":   "abc",
+		"age":           bson.M{"$gt": 18},
+		"$or":           bson.A{bson.M{"token": "t"}, bson.M{"email": "a@b.c"}},
+		"tags":          bson.M{"$in": []string{"a", "b"}},
+		"credentials":   bson.D{{Key: "secret", Value: "s"}, {Key: "kind", Value: "basic"}},
+		"nested.secret": bson.M{"$exists": true},
+	}
+	tests := []struct {
+		name     string
+		redactor *Redactor
+		want     map[string]interface{}
+	}{
+		{
+			"defaults",
+			CreateRedactor(),
+			map[string]interface{}{
+				"_id":           id,
+				"owners":        map[string]interface{}{"$in": []interface{}{id}},
+				"name":          "foo",
+				"Password":      Redacted,
+				"user.apikey":   Redacted,
+				"age":           map[string]interface{}{"$gt": 18},
+				"$or":           []interface{}{map[string]interface{}{"token": Redacted}, map[string]interface{}{"email": "a@b.c"}},
+				"tags":          map[string]interface{}{"$in": []interface{}{"a", "b"}},
+				"credentials":   map[string]interface{}{"secret": Redacted, "kind": "basic"},
+				"nested.secret": Redacted,
+			},
+		},
+		{
+			"fields",
+			CreateRedactor().SetFields("email").AddFields("name"),
+			map[string]interface{}{
+				"_id":           id,
+				"owners":        map[string]interface{}{"$in": []interface{}{id}},
+				"name":          Redacted,
+				"Password":      "hunter2",
+				"user.apikey":   "abc",
+				"age":           map[string]interface{}{"$gt": 18},
+				"$or":           []interface{}{map[string]interface{}{"token": "t"}, map[string]interface{}{"email": Redacted}},
+				"tags":          map[string]interface{}{"$in": []interface{}{"a", "b"}},
+				"credentials":   map[string]interface{}{"secret": "s", "kind": "basic"},
+				"nested.secret": map[string]interface{}{"$exists": true},
+			},
+		},
+		{
+			"values",
+			CreateRedactor().SetRedactValues(true),
+			map[string]interface{}{
+				"_id":           "?",
+				"owners":        map[string]interface{}{"$in": []interface{}{"?"}},
+				"name":          "?",
+				"Password":      Redacted,
+				"user.apikey":   Redacted,
+				"age":           map[string]interface{}{"$gt": "?"},
+				"$or":           []interface{}{map[string]interface{}{"token": Redacted}, map[string]interface{}{"email": "?"}},
+				"tags":          map[string]interface{}{"$in": []interface{}{"?", "?"}},
+				"credentials":   map[string]interface{}{"secret": Redacted, "kind": "?"},
+				"nested.secret": Redacted,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.redactor.Filter(filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := CreateRedactor().Filter(nil); got != nil {
+		t.Errorf("Filter(nil) = %v, want nil", got)
+	}
+	if (*filter)["Password"] != "hunter2" {
+		t.Error("Filter() changed the filter")
+	}
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Driver creates a database from the URL passed to Connect
+type Driver func(ctx context.Context, u *url.URL) (Database, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// Register makes a driver available to Connect for URLs with the scheme,
+// backends register themselves when imported. It panics if the driver is
+// nil or the scheme is already registered
+func Register(scheme string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if driver == nil {
+		panic("db.Register() driver is nil")
+	}
+	if _, ok := drivers[scheme]; ok {
+		panic("db.Register() called twice for scheme " + scheme)
+	}
+	drivers[scheme] = driver
+}
+
+// Drivers returns the registered schemes sorted
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	schemes := make([]string, 0, len(drivers))
+	for scheme := range drivers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Connect creates the database of the driver registered for the scheme of the
+// URL and opens it, such as "mongodb://localhost:27017/?db=app" or "mem://"
+func Connect(ctx context.Context, rawURL string) (Database, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("db.Connect() error parsing url: %v", err)
+	}
+	driversMu.RLock()
+	driver, ok := drivers[u.Scheme]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("db.Connect() error: no driver registered for scheme %q, forgotten import?", u.Scheme)
+	}
+	d, err := driver(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("db.Connect() error: %w", err)
+	}
+	if err := d.Open(ctx); err != nil {
+		// the driver may hold resources, such as files, before it is opened
+		_ = d.Close(ctx)
+		return nil, fmt.Errorf("db.Connect() error opening: %w", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"testing"
+)
+
+// fakeDatabase records whether it was opened and closed
+type fakeDatabase struct {
+	Database
+	url     *url.URL
+	opened  bool
+	closed  bool
+	openErr error
+}
+
+func (f *fakeDatabase) Open(ctx context.Context) error {
+	f.opened = true
+	return f.openErr
+}
+
+func (f *fakeDatabase) Close(ctx context.Context) error {
+	f.closed = true
+	return nil
+}
+
+func TestConnect(t *testing.T) {
+	Register("fake", func(ctx context.Context, u *url.URL) (Database, error) {
+		if u.Query().Get("fail") != "" {
+			return nil, errors.New("failed")
+		}
+		return &fakeDatabase{url: u}, nil
+	})
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"registered", "fake://host/path?db=app", false},
+		{"driver_error", "fake://?fail=1", true},
+		{"unregistered", "unknown://host", true},
+		{"invalid", "://", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Connect(context.Background(), tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Connect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			f := d.(*fakeDatabase)
+			if !f.opened || f.url.Host != "host" || f.url.Query().Get("db") != "app" {
+				t.Errorf("Connect() = %+v", f)
+			}
+		})
+	}
+
+	// a database failing to open is closed and its error kept
+	var failed *fakeDatabase
+	Register("unreachable", func(ctx context.Context, u *url.URL) (Database, error) {
+		failed = &fakeDatabase{url: u, openErr: ErrNetwork}
+		return failed, nil
+	})
+	if _, err := Connect(context.Background(), "unreachable://host"); !errors.Is(err, ErrNetwork) {
+		t.Errorf("Connect() error = %v, want ErrNetwork", err)
+	}
+	if !failed.closed {
+		t.Error("Connect() did not close the database which failed to open")
+	}
+
+	if got := Drivers(); sort.SearchStrings(got, "fake") == len(got) {
+		t.Errorf("Drivers() = %v, want fake registered", got)
+	}
+}
+
+func TestRegister_Panics(t *testing.T) {
+	driver := func(ctx context.Context, u *url.URL) (Database, error) { return nil, nil }
+	Register("twice", driver)
+	tests := []struct {
+		name   string
+		scheme string
+		driver Driver
+	}{
+		{"nil", "nil", nil},
+		{"twice", "twice", driver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register() expected panic")
+				}
+			}()
+			Register(tt.scheme, tt.driver)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+)
+
+// OperationType is the kind of change made to a document
+type OperationType string
+
+const (
+	OperationInsert  OperationType = "insert"
+	OperationUpdate  OperationType = "update"
+	OperationReplace OperationType = "replace"
+	OperationDelete  OperationType = "delete"
+)
+
+// ChangeEvent describes a single change made to a document within a collection
+type ChangeEvent struct {
+	OperationType OperationType
+	Collection    string
+	// DocumentKey holds the _id of the changed document
+	DocumentKey bson.Raw
+	// FullDocument is the document after the change, it is nil for deletes
+	FullDocument bson.Raw
+	// ResumeToken can be passed to WatchOptions.ResumeAfter to
+	// continue watching after this event
+	ResumeToken bson.Raw
+	// Registry is the registry of the database which decodes the full
+	// document, bson.DefaultRegistry when nil
+	Registry *bsoncodec.Registry
+}
+
+// Decode unmarshals the full document of the event into v with the registry
+// of the event
+func (e *ChangeEvent) Decode(v interface{}) error {
+	if e.FullDocument == nil {
+		return errors.New("change event has no full document")
+	}
+	registry := e.Registry
+	if registry == nil {
+		registry = bson.DefaultRegistry
+	}
+	return bson.UnmarshalWithRegistry(registry, e.FullDocument, v)
+}
+
+// ChangeStream iterates over the change events of a collection
+type ChangeStream interface {
+	// Next blocks until the next event is available and returns true,
+	// it returns false when the stream is closed or ctx is done
+	Next(ctx context.Context) bool
+	// Event returns the event read by the last call to Next
+	Event() *ChangeEvent
+	// Err returns the error which stopped the stream
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// WatchOptions configures a change stream
+type WatchOptions struct {
+	// ResumeAfter starts the stream after the event with the resume token
+	ResumeAfter bson.Raw
+}
+
+func CreateWatchOptions() *WatchOptions {
+	return &WatchOptions{}
+}
+
+// SetResumeAfter starts the stream after the event with the resume token
+func (o *WatchOptions) SetResumeAfter(token bson.Raw) *WatchOptions {
+	o.ResumeAfter = token
+	return o
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+func TestChangeEvent_Decode(t *testing.T) {
+	doc, err := bson.Marshal(bson.M{"name": "foo", "n": int32(1)})
+	if err != nil {
+		t.Fatal("error marshalling:", err)
+	}
+	// the registry decodes int32 values as int64
+	registry := bson.NewRegistryBuilder().RegisterTypeMapEntry(bsontype.Int32, reflect.TypeOf(int64(0))).Build()
+	tests := []struct {
+		name    string
+		event   *ChangeEvent
+		want    bson.M
+		wantErr bool
+	}{
+		{"full_document", &ChangeEvent{OperationType: OperationInsert, FullDocument: doc}, bson.M{"name": "foo", "n": int32(1)}, false},
+		{"registry", &ChangeEvent{OperationType: OperationInsert, FullDocument: doc, Registry: registry}, bson.M{"name": "foo", "n": int64(1)}, false},
+		{"delete", &ChangeEvent{OperationType: OperationDelete}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bson.M
+			if err := tt.event.Decode(&got); (err != nil) != tt.wantErr {
+				t.Fatalf("ChangeEvent.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChangeEvent.Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchOptions_SetResumeAfter(t *testing.T) {
+	token, _ := bson.Marshal(bson.M{"_data": "1"})
+	want := &WatchOptions{ResumeAfter: token}
+	if got := CreateWatchOptions().SetResumeAfter(token); !reflect.DeepEqual(got, want) {
+		t.Errorf("WatchOptions.SetResumeAfter() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,559 @@
+// Package dbtest holds a conformance test suite describing the behaviour
+// every db.Database implementation is expected to share
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// Collection is the collection used by the suite
+const Collection = "dbtest_items"
+
+// EmptyCollection is a collection the suite never inserts into
+const EmptyCollection = "dbtest_empty"
+
+// SearchFields are the fields of Collection searched by the suite,
+// backends requiring a text index must create one upon them
+var SearchFields = []string{"name", "description"}
+
+// Factory returns an empty database for a single test, cleaning
+// it up afterwards with t.Cleanup when needed
+type Factory func(t *testing.T) db.Database
+
+// Item is the document stored by the suite
+type Item struct {
+	ID          string   `bson:"_id"`
+	Name        string   `bson:"name"`
+	Description string   `bson:"description"`
+	Price       int      `bson:"price"`
+	Tags        []string `bson:"tags"`
+}
+
+// items are inserted into the database of every test
+var items = []Item{
+	{ID: "1", Name: "Laptop", Description: "A light laptop for travelling laptop users", Price: 1200, Tags: []string{"electronics", "computers"}},
+	{ID: "2", Name: "Desk", Description: "A standing desk", Price: 300, Tags: []string{"furniture"}},
+	{ID: "3", Name: "Mouse", Description: "A wireless mouse for any laptop", Price: 25, Tags: []string{"electronics"}},
+	{ID: "4", Name: "Chair", Description: "An office chair", Price: 150, Tags: []string{"furniture"}},
+	{ID: "5", Name: "Monitor", Description: "A wide monitor", Price: 400, Tags: []string{"electronics", "displays"}},
+}
+
+// RunConformance runs the suite against the databases returned by factory
+func RunConformance(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, d db.Database)
+	}{
+		{"Insert_FindOne", testInsertFindOne},
+		{"FindAll_Filters", testFindAllFilters},
+		{"FindAll_Sort", testFindAllSort},
+		{"FindAll_Paging", testFindAllPaging},
+		{"Update", testUpdate},
+		{"Upsert", testUpsert},
+		{"Delete", testDelete},
+		{"Search", testSearch},
+		{"Consistency", testConsistency},
+		{"Errors", testErrors},
+		{"Concurrency", testConcurrency},
+		{"Watch", testWatch},
+		{"Health", testHealth},
+		{"Lifecycle", testLifecycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := factory(t)
+			for _, item := range items {
+				if err := d.Insert(Collection, item); err != nil {
+					t.Fatal("Insert() error:", err)
+				}
+			}
+			tt.test(t, d)
+		})
+	}
+}
+
+// ids returns the ids of the items in order
+func ids(items []Item) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+// findIDs returns the ids of the items matching the filter
+func findIDs(t *testing.T, d db.Database, filter *db.Filter, opts *db.Options) []string {
+	t.Helper()
+	var found []Item
+	if err := d.FindAll(Collection, &found, filter, opts); err != nil {
+		t.Fatalf("FindAll(%v) error: %v", filter, err)
+	}
+	return ids(found)
+}
+
+func sorted(ids []string) []string {
+	sort.Strings(ids)
+	return ids
+}
+
+func testInsertFindOne(t *testing.T, d db.Database) {
+	var got Item
+	if err := d.FindOne(Collection, &got, &db.Filter{"_id": "3"}, nil); err != nil {
+		t.Fatal("FindOne() error:", err)
+	}
+	if !reflect.DeepEqual(got, items[2]) {
+		t.Errorf("FindOne() = %+v, want %+v", got, items[2])
+	}
+
+	ptr := &Item{ID: "6", Name: "Lamp", Price: 40, Tags: []string{}}
+	if err := d.Insert(Collection, ptr); err != nil {
+		t.Fatal("Insert() pointer error:", err)
+	}
+	got = Item{}
+	if err := d.FindOne(Collection, &got, &db.Filter{"name": "Lamp"}, nil); err != nil {
+		t.Fatal("FindOne() error:", err)
+	}
+	if !reflect.DeepEqual(got, *ptr) {
+		t.Errorf("FindOne() = %+v, want %+v", got, *ptr)
+	}
+}
+
+func testFindAllFilters(t *testing.T, d db.Database) {
+	tests := []struct {
+		name   string
+		filter *db.Filter
+		want   []string
+	}{
+		{"nil", nil, []string{"1", "2", "3", "4", "5"}},
+		{"empty", &db.Filter{}, []string{"1", "2", "3", "4", "5"}},
+		{"equal", &db.Filter{"name": "Desk"}, []string{"2"}},
+		{"multiple_fields", &db.Filter{"tags": "electronics", "price": 25}, []string{"3"}},
+		{"array_element", &db.Filter{"tags": "furniture"}, []string{"2", "4"}},
+		{"no_match", &db.Filter{"name": "Sofa"}, nil},
+		{"$ne", &db.Filter{"tags": map[string]interface{}{"$ne": "electronics"}}, []string{"2", "4"}},
+		{"$gt", &db.Filter{"price": map[string]interface{}{"$gt": 300}}, []string{"1", "5"}},
+		{"$gte_$lt", &db.Filter{"price": map[string]interface{}{"$gte": 150, "$lt": 400}}, []string{"2", "4"}},
+		{"$in", &db.Filter{"name": map[string]interface{}{"$in": []string{"Desk", "Chair", "Sofa"}}}, []string{"2", "4"}},
+		{"$nin", &db.Filter{"tags": map[string]interface{}{"$nin": []string{"electronics", "displays"}}}, []string{"2", "4"}},
+		{"$exists", &db.Filter{"color": map[string]interface{}{"$exists": false}}, []string{"1", "2", "3", "4", "5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sorted(findIDs(t, d, tt.filter, nil)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll(%v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func testFindAllSort(t *testing.T, d db.Database) {
+	tests := []struct {
+		name   string
+		filter *db.Filter
+		opts   *db.Options
+		want   []string
+	}{
+		{"ascending", nil, db.CreateOptions().SetSort("price", 1), []string{"3", "4", "2", "5", "1"}},
+		{"descending", nil, db.CreateOptions().SetSort("price", -1), []string{"1", "5", "2", "4", "3"}},
+		{"string", nil, db.CreateOptions().SetSort("name", 1), []string{"4", "2", "1", "5", "3"}},
+		{"filtered", &db.Filter{"tags": "electronics"}, db.CreateOptions().SetSort("price", -1), []string{"1", "5", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findIDs(t, d, tt.filter, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testFindAllPaging(t *testing.T, d db.Database) {
+	byPrice := func() *db.Options { return db.CreateOptions().SetSort("price", 1) }
+	tests := []struct {
+		name string
+		opts *db.Options
+		want []string
+	}{
+		{"limit", byPrice().SetLimit(2), []string{"3", "4"}},
+		{"skip", byPrice().SetSkip(3), []string{"5", "1"}},
+		{"skip_limit", byPrice().SetSkip(1).SetLimit(2), []string{"4", "2"}},
+		{"skip_past_end", byPrice().SetSkip(10), nil},
+		{"limit_past_end", byPrice().SetSkip(4).SetLimit(10), []string{"1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findIDs(t, d, nil, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var first Item
+	if err := d.FindOne(Collection, &first, &db.Filter{"tags": "furniture"}, byPrice().SetSkip(1)); err != nil {
+		t.Fatal("FindOne() error:", err)
+	}
+	if first.ID != "2" {
+		t.Errorf("FindOne() with sort and skip = %v, want 2", first.ID)
+	}
+}
+
+func testUpdate(t *testing.T, d db.Database) {
+	updated := items[1]
+	updated.Price = 350
+	if err := d.Update(Collection, updated, &db.Filter{"_id": "2"}); err != nil {
+		t.Fatal("Update() error:", err)
+	}
+	var got Item
+	if err := d.FindOne(Collection, &got, &db.Filter{"_id": "2"}, nil); err != nil {
+		t.Fatal("FindOne() error:", err)
+	}
+	if !reflect.DeepEqual(got, updated) {
+		t.Errorf("FindOne() after Update() = %+v, want %+v", got, updated)
+	}
+	if got := findIDs(t, d, nil, nil); len(got) != len(items) {
+		t.Errorf("Update() changed the number of documents: %v", got)
+	}
+
+	// updating a document to the values it holds is not an error
+	if err := d.Update(Collection, updated, &db.Filter{"_id": "2"}); err != nil {
+		t.Errorf("Update() unchanged error: %v", err)
+	}
+	if err := d.Update(Collection, Item{ID: "9"}, &db.Filter{"_id": "9"}); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Update() error = %v, want db.ErrNotFound when no document matches", err)
+	}
+	if got := findIDs(t, d, &db.Filter{"_id": "9"}, nil); len(got) != 0 {
+		t.Errorf("Update() inserted a document: %v", got)
+	}
+
+	// the document is replaced, fields missing from the object are removed
+	partial := struct {
+		ID   string `bson:"_id"`
+		Name string `bson:"name"`
+	}{ID: "3", Name: "Trackball"}
+	if err := d.Update(Collection, partial, &db.Filter{"_id": "3"}); err != nil {
+		t.Fatal("Update() partial error:", err)
+	}
+	var doc map[string]interface{}
+	if err := d.FindOne(Collection, &doc, &db.Filter{"_id": "3"}, nil); err != nil {
+		t.Fatal("FindOne() error:", err)
+	}
+	if _, ok := doc["price"]; ok || doc["name"] != "Trackball" {
+		t.Errorf("FindOne() after Update() = %v, want only _id and name", doc)
+	}
+
+	// a collection which was never written to matches nothing
+	if err := d.Update(EmptyCollection, Item{ID: "1"}, &db.Filter{"_id": "1"}); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Update() on an empty collection error = %v, want db.ErrNotFound", err)
+	}
+}
+
+func testUpsert(t *testing.T, d db.Database) {
+	updated := items[0]
+	updated.Name = "Notebook"
+	if err := d.Upsert(Collection, updated, &db.Filter{"_id": "1"}); err != nil {
+		t.Fatal("Upsert() existing error:", err)
+	}
+	inserted := Item{ID: "7", Name: "Sofa", Price: 900, Tags: []string{"furniture"}}
+	if err := d.Upsert(Collection, inserted, &db.Filter{"_id": "7"}); err != nil {
+		t.Fatal("Upsert() new error:", err)
+	}
+
+	for _, want := range []Item{updated, inserted} {
+		var got Item
+		if err := d.FindOne(Collection, &got, &db.Filter{"_id": want.ID}, nil); err != nil {
+			t.Fatal("FindOne() error:", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("FindOne() after Upsert() = %+v, want %+v", got, want)
+		}
+	}
+	if got := findIDs(t, d, nil, nil); len(got) != len(items)+1 {
+		t.Errorf("Upsert() documents = %v, want %d", got, len(items)+1)
+	}
+
+	// like Update the document is replaced, fields missing from the object are removed
+	partial := struct {
+		ID   string `bson:"_id"`
+		Name string `bson:"name"`
+	}{ID: "2", Name: "Table"}
+	if err := d.Upsert(Collection, partial, &db.Filter{"_id": "2"}); err != nil {
+		t.Fatal("Upsert() partial error:", err)
+	}
+	var doc map[string]interface{}
+	if err := d.FindOne(Collection, &doc, &db.Filter{"_id": "2"}, nil); err != nil {
+		t.Fatal("FindOne() error:", err)
+	}
+	if _, ok := doc["price"]; ok || doc["name"] != "Table" {
+		t.Errorf("FindOne() after Upsert() = %v, want only _id and name", doc)
+	}
+}
+
+func testDelete(t *testing.T, d db.Database) {
+	if err := d.Delete(Collection, &db.Filter{"_id": "4"}); err != nil {
+		t.Fatal("Delete() error:", err)
+	}
+	if got, want := sorted(findIDs(t, d, nil, nil)), []string{"1", "2", "3", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAll() after Delete() = %v, want %v", got, want)
+	}
+	// only a single document is deleted
+	if err := d.Delete(Collection, &db.Filter{"tags": "electronics"}); err != nil {
+		t.Fatal("Delete() error:", err)
+	}
+	if got := findIDs(t, d, &db.Filter{"tags": "electronics"}, nil); len(got) != 2 {
+		t.Errorf("Delete() removed %d documents, want 1", 3-len(got))
+	}
+	if err := d.Delete(Collection, &db.Filter{"_id": "4"}); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Delete() error = %v, want db.ErrNotFound when no document matches", err)
+	}
+	// a collection which was never written to matches nothing
+	if err := d.Delete(EmptyCollection, &db.Filter{"_id": "1"}); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Delete() on an empty collection error = %v, want db.ErrNotFound", err)
+	}
+}
+
+func testSearch(t *testing.T, d db.Database) {
+	var found []Item
+	if err := d.Search(Collection, "laptop", SearchFields, &found); err != nil {
+		t.Fatal("Search() error:", err)
+	}
+	if got, want := ids(found), []string{"1", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search() = %v, want %v by relevance", got, want)
+	}
+
+	found = nil
+	if err := d.Search(Collection, "elephant", SearchFields, &found); err != nil {
+		t.Fatal("Search() error:", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("Search() = %v, want no results", ids(found))
+	}
+
+	found = nil
+	opts := db.CreateOptions().SetLimit(1)
+	filter := &db.Filter{"price": map[string]interface{}{"$lt": 100}}
+	if err := d.SearchWithOptions(Collection, "laptop", SearchFields, &found, filter, opts); err != nil {
+		t.Fatal("SearchWithOptions() error:", err)
+	}
+	if got, want := ids(found), []string{"3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchWithOptions() = %v, want %v", got, want)
+	}
+}
+
+func testConsistency(t *testing.T, d db.Database) {
+	majority := db.CreateWriteOptions().SetMajority().SetJournal(true)
+	if err := d.Insert(Collection, Item{ID: "6", Name: "Desk", Price: 300}, majority); err != nil {
+		t.Fatal("Insert() with write options error:", err)
+	}
+	if err := d.Update(Collection, Item{ID: "6", Name: "Desk", Price: 250}, &db.Filter{"_id": "6"}, majority); err != nil {
+		t.Fatal("Update() with write options error:", err)
+	}
+	opts := db.CreateOptions().SetReadPreference(db.ReadPrimary).SetReadConcern(db.ReadConcernMajority)
+	var item Item
+	if err := d.FindOne(Collection, &item, &db.Filter{"_id": "6"}, opts); err != nil || item.Price != 250 {
+		t.Errorf("FindOne() with read options = %+v, %v", item, err)
+	}
+	if err := d.Delete(Collection, &db.Filter{"_id": "6"}, majority); err != nil {
+		t.Fatal("Delete() with write options error:", err)
+	}
+
+	invalid := db.CreateOptions().SetReadConcern("strong")
+	if err := d.FindOne(Collection, &item, &db.Filter{"_id": "1"}, invalid); !errors.Is(err, db.ErrInvalidOptions) {
+		t.Errorf("FindOne() error = %v, want db.ErrInvalidOptions", err)
+	}
+	unacknowledged := db.CreateWriteOptions().SetWriteConcern("0").SetJournal(true)
+	if err := d.Insert(Collection, Item{ID: "7"}, unacknowledged); !errors.Is(err, db.ErrInvalidOptions) {
+		t.Errorf("Insert() error = %v, want db.ErrInvalidOptions", err)
+	}
+}
+
+func testErrors(t *testing.T, d db.Database) {
+	var item Item
+	if err := d.FindOne(Collection, &item, &db.Filter{"_id": "9"}, nil); err == nil {
+		t.Errorf("FindOne() expected error when no document matches")
+	}
+	if err := d.FindOne(Collection, item, &db.Filter{"_id": "1"}, nil); err == nil {
+		t.Errorf("FindOne() expected error for a non pointer")
+	}
+	var found []Item
+	if err := d.FindAll(Collection, found, nil, nil); err == nil {
+		t.Errorf("FindAll() expected error for a non pointer")
+	}
+	if err := d.Insert(Collection, nil); err == nil {
+		t.Errorf("Insert() expected error for nil")
+	}
+}
+
+func testConcurrency(t *testing.T, d db.Database) {
+	const workers, inserts = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*inserts*2)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < inserts; i++ {
+				item := Item{ID: fmt.Sprintf("w%d-%d", w, i), Name: "Item", Price: i, Tags: []string{"concurrent"}}
+				if err := d.Insert(Collection, item); err != nil {
+					errs <- err
+				}
+				var found []Item
+				if err := d.FindAll(Collection, &found, &db.Filter{"tags": "concurrent"}, nil); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error("concurrent call error:", err)
+	}
+	if got := findIDs(t, d, &db.Filter{"tags": "concurrent"}, nil); len(got) != workers*inserts {
+		t.Errorf("FindAll() found %d concurrent documents, want %d", len(got), workers*inserts)
+	}
+}
+
+// nextEvent reads the next event of the stream, failing after a few seconds
+func nextEvent(t *testing.T, s db.ChangeStream) *db.ChangeEvent {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if !s.Next(ctx) {
+		t.Fatal("ChangeStream.Next() error:", s.Err())
+	}
+	return s.Event()
+}
+
+func testWatch(t *testing.T, d db.Database) {
+	ctx := context.Background()
+	stream, err := d.Watch(ctx, Collection, &db.Filter{"_id": "6"}, nil)
+	if err != nil {
+		t.Fatal("Watch() error:", err)
+	}
+	defer stream.Close(ctx)
+
+	// changes to other documents are not streamed, deletes included
+	lamp := Item{ID: "6", Name: "Lamp", Price: 40, Tags: []string{"furniture"}}
+	changes := []func() error{
+		func() error { return d.Insert(Collection, Item{ID: "7", Name: "Sofa"}) },
+		func() error { return d.Insert(Collection, lamp) },
+		func() error { lamp.Price = 35; return d.Update(Collection, lamp, &db.Filter{"_id": "6"}) },
+		func() error { lamp.Price = 30; return d.Upsert(Collection, lamp, &db.Filter{"_id": "6"}) },
+		func() error { return d.Delete(Collection, &db.Filter{"_id": "7"}) },
+		func() error { return d.Delete(Collection, &db.Filter{"_id": "6"}) },
+	}
+	for i, change := range changes {
+		if err := change(); err != nil {
+			t.Fatalf("change %d error: %v", i, err)
+		}
+	}
+
+	want := []struct {
+		op    db.OperationType
+		price int
+	}{
+		{db.OperationInsert, 40},
+		{db.OperationReplace, 35},
+		{db.OperationReplace, 30},
+		{db.OperationDelete, 0},
+	}
+	for _, w := range want {
+		event := nextEvent(t, stream)
+		if event.OperationType != w.op {
+			t.Fatalf("ChangeStream event = %v, want %v", event.OperationType, w.op)
+		}
+		if id := event.DocumentKey.Lookup("_id").StringValue(); id != "6" {
+			t.Errorf("%v event document key = %v, want 6", w.op, id)
+		}
+		if w.op == db.OperationDelete {
+			if event.FullDocument != nil {
+				t.Errorf("delete event full document = %v, want nil", event.FullDocument)
+			}
+			continue
+		}
+		var got Item
+		if err := event.Decode(&got); err != nil {
+			t.Fatalf("%v event Decode() error: %v", w.op, err)
+		}
+		if got.ID != "6" || got.Price != w.price {
+			t.Errorf("%v event full document = %+v, want price %d", w.op, got, w.price)
+		}
+	}
+}
+
+func testHealth(t *testing.T, d db.Database) {
+	ctx := context.Background()
+	if err := d.Ping(ctx); err != nil {
+		t.Fatal("Ping() error:", err)
+	}
+	h := d.Health(ctx)
+	if !h.Healthy || h.Err != nil {
+		t.Errorf("Health() = %+v, want healthy", h)
+	}
+	if h.Topology == "" || h.CheckedAt.IsZero() || h.Latency < 0 {
+		t.Errorf("Health() = %+v, want the topology, latency and time of the check", h)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := d.Ping(canceled); err == nil {
+		t.Error("Ping() expected error for a canceled context")
+	}
+}
+
+func testLifecycle(t *testing.T, d db.Database) {
+	ctx := context.Background()
+	if err := d.Close(ctx); err != nil {
+		t.Fatal("Close() error:", err)
+	}
+	// closing a closed database does nothing
+	if err := d.Close(ctx); err != nil {
+		t.Error("Close() closed error:", err)
+	}
+
+	var item Item
+	var found []Item
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{"Ping", func() error { return d.Ping(ctx) }},
+		{"Insert", func() error { return d.Insert(Collection, Item{ID: "6"}) }},
+		{"FindOne", func() error { return d.FindOne(Collection, &item, &db.Filter{"_id": "1"}, nil) }},
+		{"FindAll", func() error { return d.FindAll(Collection, &found, nil, nil) }},
+		{"Update", func() error { return d.Update(Collection, items[0], &db.Filter{"_id": "1"}) }},
+		{"Upsert", func() error { return d.Upsert(Collection, items[0], &db.Filter{"_id": "1"}) }},
+		{"Delete", func() error { return d.Delete(Collection, &db.Filter{"_id": "1"}) }},
+		{"Search", func() error { return d.Search(Collection, "laptop", SearchFields, &found) }},
+		{"Watch", func() error { _, err := d.Watch(ctx, Collection, nil, nil); return err }},
+	}
+	for _, c := range calls {
+		var closed *db.ClosedError
+		if err := c.call(); !errors.As(err, &closed) {
+			t.Errorf("%v() after Close() error = %v, want *db.ClosedError", c.name, err)
+		}
+	}
+	if h := d.Health(ctx); h.Healthy || !errors.Is(h.Err, db.ErrClosed) {
+		t.Errorf("Health() after Close() = %+v, want unhealthy with db.ErrClosed", h)
+	}
+
+	// the database can be opened again
+	if err := d.Open(ctx); err != nil {
+		t.Fatal("Open() error:", err)
+	}
+	if err := d.Insert(Collection, Item{ID: "6", Name: "Lamp"}); err != nil {
+		t.Fatal("Insert() after Open() error:", err)
+	}
+	if err := d.FindOne(Collection, &item, &db.Filter{"_id": "6"}, nil); err != nil || item.Name != "Lamp" {
+		t.Errorf("FindOne() after Open() = %+v, %v", item, err)
+	}
+}
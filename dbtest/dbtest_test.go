@@ -0,0 +1,72 @@
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"github.com/sschwartz96/stockpile/mock"
+	"github.com/sschwartz96/stockpile/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestConformance_Mock(t *testing.T) {
+	RunConformance(t, func(t *testing.T) db.Database { return mock.CreateDB() })
+}
+
+func TestConformance_MockBSON(t *testing.T) {
+	RunConformance(t, func(t *testing.T) db.Database { return mock.CreateBSONDB(nil) })
+}
+
+func TestConformance_MockFile(t *testing.T) {
+	RunConformance(t, func(t *testing.T) db.Database {
+		d, err := mock.CreateFileDB(t.TempDir(), nil)
+		if err != nil {
+			t.Fatal("mock.CreateFileDB() error:", err)
+		}
+		t.Cleanup(func() { d.Close(context.Background()) })
+		return d
+	})
+}
+
+// TestConformance_Mongo runs the suite against the MongoDB server
+// at STOCKPILE_MONGO_URI, it is skipped when the variable is not set.
+// The server must be a replica set for the change streams, see README.md
+func TestConformance_Mongo(t *testing.T) {
+	uri := os.Getenv("STOCKPILE_MONGO_URI")
+	if uri == "" {
+		t.Skip("STOCKPILE_MONGO_URI is not set")
+	}
+	RunConformance(t, func(t *testing.T) db.Database {
+		dbName := fmt.Sprintf("dbtest_%d", time.Now().UnixNano())
+		searchIndices := map[string]map[string]bool{Collection: {}}
+		for _, field := range SearchFields {
+			searchIndices[Collection][field] = true
+		}
+		c, err := mongodb.NewMongoClient(dbName, []string{Collection}, options.Client().ApplyURI(uri), searchIndices)
+		if err != nil {
+			t.Fatal("mongodb.NewMongoClient() error:", err)
+		}
+		ctx := context.Background()
+		if err := c.Open(ctx); err != nil {
+			t.Fatal("MongoClient.Open() error:", err)
+		}
+		index := bson.D{}
+		for _, field := range SearchFields {
+			index = append(index, bson.E{Key: field, Value: "text"})
+		}
+		if _, err := c.Database(dbName).Collection(Collection).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: index}); err != nil {
+			t.Fatal("error creating text index:", err)
+		}
+		t.Cleanup(func() {
+			c.Database(dbName).Drop(ctx)
+			c.Close(ctx)
+		})
+		return c
+	})
+}
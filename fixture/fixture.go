@@ -0,0 +1,284 @@
+// Package fixture seeds a db.Database, such as mock.DB or mongodb.MongoClient,
+// with documents read from JSON, MongoDB Extended JSON and YAML files
+package fixture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"gopkg.in/yaml.v2"
+)
+
+// Loader reads fixture files and inserts their documents into a database.
+//
+// A file holds either an array of documents, inserted into the collection
+// named after the file, or an object mapping collection names to arrays of
+// documents. Files ending in .json or .ejson are read as Extended JSON, which
+// also accepts plain JSON, and files ending in .yaml or .yml as YAML, whose
+// values can use the Extended JSON notation such as {$oid: ...}
+type Loader struct {
+	types    map[string]reflect.Type
+	registry *bsoncodec.Registry
+}
+
+// Fixture holds the documents of a collection in the order they were read
+type Fixture struct {
+	Collection string
+	Documents  []interface{}
+}
+
+func CreateLoader() *Loader {
+	return &Loader{types: make(map[string]reflect.Type), registry: bson.DefaultRegistry}
+}
+
+// Register decodes the documents of the collection into the type of example,
+// documents of unregistered collections are decoded into bson.D
+func (l *Loader) Register(collection string, example interface{}) *Loader {
+	t := reflect.TypeOf(example)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	l.types[collection] = t
+	return l
+}
+
+// SetRegistry sets the bson registry used to decode documents into registered types
+func (l *Loader) SetRegistry(registry *bsoncodec.Registry) *Loader {
+	l.registry = registry
+	return l
+}
+
+// Load reads the fixture files, or every fixture file within directories,
+// and inserts their documents into the database
+func (l *Loader) Load(database db.Database, paths ...string) error {
+	fixtures, err := l.Read(paths...)
+	if err != nil {
+		return err
+	}
+	for _, f := range fixtures {
+		for _, doc := range f.Documents {
+			if err := database.Insert(f.Collection, doc); err != nil {
+				return fmt.Errorf("fixture.Loader.Load() error inserting into %v: %v", f.Collection, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Read reads and decodes the fixture files, or every fixture file within directories
+func (l *Loader) Read(paths ...string) ([]Fixture, error) {
+	var fixtures []Fixture
+	for _, path := range paths {
+		files, err := fixtureFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("fixture.Loader.Read() error: %v", err)
+		}
+		for _, file := range files {
+			f, err := l.readFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("fixture.Loader.Read() error in %v: %v", file, err)
+			}
+			fixtures = append(fixtures, f...)
+		}
+	}
+	return fixtures, nil
+}
+
+// fixtureFiles returns the path, or the fixture files within it sorted by name if it is a directory
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isFixtureFile(entry.Name()) {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func isFixtureFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".ejson", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func (l *Loader) readFile(path string) ([]Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".yaml", ".yml":
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, err
+		}
+	case ".json", ".ejson":
+	default:
+		return nil, fmt.Errorf("unsupported file extension %q", ext)
+	}
+	return l.decode(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), data)
+}
+
+// decode decodes Extended JSON holding either the documents of the collection
+// or an object mapping collection names to documents
+func (l *Loader) decode(collection string, data []byte) ([]Fixture, error) {
+	// Extended JSON can only be unmarshalled from a document, so wrap the content
+	wrapped := append(append([]byte(`{"fixture":`), data...), '}')
+	var doc bson.Raw
+	if err := bson.UnmarshalExtJSON(wrapped, false, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing: %v", err)
+	}
+	content := doc.Lookup("fixture")
+
+	switch content.Type {
+	case bsontype.Array:
+		f, err := l.decodeDocuments(collection, content)
+		if err != nil {
+			return nil, err
+		}
+		return []Fixture{f}, nil
+	case bsontype.EmbeddedDocument:
+		elements, err := content.Document().Elements()
+		if err != nil {
+			return nil, err
+		}
+		fixtures := make([]Fixture, len(elements))
+		for i, element := range elements {
+			if fixtures[i], err = l.decodeDocuments(element.Key(), element.Value()); err != nil {
+				return nil, err
+			}
+		}
+		return fixtures, nil
+	}
+	return nil, errors.New("fixture must be an array of documents or an object of collections")
+}
+
+// decodeDocuments decodes the array of documents into the type registered for the collection
+func (l *Loader) decodeDocuments(collection string, array bson.RawValue) (Fixture, error) {
+	f := Fixture{Collection: collection}
+	values, ok := array.ArrayOK()
+	if !ok {
+		return f, fmt.Errorf("collection %v is not an array of documents", collection)
+	}
+	elements, err := values.Values()
+	if err != nil {
+		return f, err
+	}
+	t, ok := l.types[collection]
+	if !ok {
+		t = reflect.TypeOf(bson.D{})
+	}
+	for i, element := range elements {
+		raw, ok := element.DocumentOK()
+		if !ok {
+			return f, fmt.Errorf("element %d of collection %v is not a document", i, collection)
+		}
+		doc := reflect.New(t)
+		if err := bson.UnmarshalWithRegistry(l.registry, raw, doc.Interface()); err != nil {
+			return f, fmt.Errorf("error decoding element %d of collection %v into %v: %v", i, collection, t, err)
+		}
+		f.Documents = append(f.Documents, doc.Elem().Interface())
+	}
+	return f, nil
+}
+
+// yamlToJSON converts YAML into JSON so it can be read as Extended JSON
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("error parsing: %v", err)
+	}
+	// decoding into yaml.MapSlice keeps the order of the keys of every object
+	if array, ok := v.([]interface{}); ok {
+		var objects []yaml.MapSlice
+		if err := yaml.Unmarshal(data, &objects); err == nil {
+			for i := range objects {
+				array[i] = objects[i]
+			}
+		}
+	} else if _, ok := v.(map[interface{}]interface{}); ok {
+		var object yaml.MapSlice
+		if err := yaml.Unmarshal(data, &object); err == nil {
+			v = object
+		}
+	}
+	return json.Marshal(jsonValue(v))
+}
+
+// jsonValue converts the maps decoded from YAML, which can hold keys of any type,
+// into maps with string keys
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		m := make(orderedMap, len(v))
+		for i, item := range v {
+			m[i] = orderedItem{key: fmt.Sprint(item.Key), value: jsonValue(item.Value)}
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = jsonValue(item)
+		}
+		return s
+	}
+	return v
+}
+
+type orderedItem struct {
+	key   string
+	value interface{}
+}
+
+// orderedMap marshals into a JSON object keeping the order of its items
+type orderedMap []orderedItem
+
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	b := []byte{'{'}
+	for i, item := range m {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		key, err := json.Marshal(item.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(item.value)
+		if err != nil {
+			return nil, err
+		}
+		b = append(append(append(b, key...), ':'), value...)
+	}
+	return append(b, '}'), nil
+}
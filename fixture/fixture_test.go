@@ -0,0 +1,111 @@
+package fixture
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"github.com/sschwartz96/stockpile/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type user struct {
+	ID   string `bson:"_id"`
+	Name string `bson:"name"`
+	Age  int    `bson:"age"`
+}
+
+type post struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Title   string             `bson:"title"`
+	Author  string             `bson:"author"`
+	Tags    []string           `bson:"tags"`
+	Created time.Time          `bson:"created"`
+}
+
+func TestLoader_Read(t *testing.T) {
+	oid, _ := primitive.ObjectIDFromHex("5f8f4b1e9d3b2a1c4e6f7a8b")
+	l := CreateLoader().Register("users", user{}).Register("posts", &post{})
+	tests := []struct {
+		name    string
+		path    string
+		want    []Fixture
+		wantErr bool
+	}{
+		{"json", "testdata/users.json", []Fixture{{Collection: "users", Documents: []interface{}{
+			user{ID: "1", Name: "alice", Age: 30},
+			user{ID: "2", Name: "bob", Age: 25},
+		}}}, false},
+		{"directory", "testdata/dir", []Fixture{
+			{Collection: "users", Documents: []interface{}{user{ID: "3", Name: "carol", Age: 41}}},
+			{Collection: "counters", Documents: []interface{}{
+				bson.D{{Key: "_id", Value: "posts"}, {Key: "count", Value: int64(2)}},
+			}},
+			{Collection: "users", Documents: []interface{}{user{ID: "4", Name: "dave", Age: 19}}},
+		}, false},
+		{"invalid", "testdata/invalid.json", nil, true},
+		{"missing", "testdata/missing.json", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Read(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Loader.Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Loader.Read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	posts, err := l.Read("testdata/posts.yaml")
+	if err != nil {
+		t.Fatal("Loader.Read() error:", err)
+	}
+	want := post{
+		ID:      oid,
+		Title:   "First post",
+		Author:  "1",
+		Tags:    []string{"go", "mongo"},
+		Created: time.Date(2020, 10, 20, 12, 0, 0, 0, time.UTC),
+	}
+	if len(posts) != 1 || len(posts[0].Documents) != 2 {
+		t.Fatalf("Loader.Read() yaml = %v", posts)
+	}
+	if got := posts[0].Documents[0].(post); got.ID != want.ID || !got.Created.Equal(want.Created) ||
+		got.Title != want.Title || !reflect.DeepEqual(got.Tags, want.Tags) {
+		t.Errorf("Loader.Read() yaml = %v, want %v", got, want)
+	}
+}
+
+func TestLoader_Load(t *testing.T) {
+	for name, d := range map[string]*mock.DB{"go": mock.CreateDB(), "bson": mock.CreateBSONDB(nil)} {
+		t.Run(name, func(t *testing.T) {
+			l := CreateLoader().Register("users", user{}).Register("posts", post{})
+			if err := l.Load(d, "testdata/users.json", "testdata/posts.yaml", "testdata/dir"); err != nil {
+				t.Fatal("Loader.Load() error:", err)
+			}
+			var users []user
+			if err := d.FindAll("users", &users, nil, db.CreateOptions().SetSort("age", 1)); err != nil {
+				t.Fatal("DB.FindAll() error:", err)
+			}
+			var names []string
+			for _, u := range users {
+				names = append(names, u.Name)
+			}
+			if want := []string{"dave", "bob", "alice", "carol"}; !reflect.DeepEqual(names, want) {
+				t.Errorf("DB.FindAll() users = %v, want %v", names, want)
+			}
+			var p post
+			if err := d.FindOne("posts", &p, &db.Filter{"author": "2"}, nil); err != nil || p.Title != "Second post" {
+				t.Errorf("DB.FindOne() post = %v, %v", p, err)
+			}
+		})
+	}
+
+	if err := CreateLoader().Load(mock.CreateDB(), "testdata/invalid.json"); err == nil {
+		t.Errorf("Loader.Load() expected error")
+	}
+}
@@ -3,7 +3,7 @@ module github.com/sschwartz96/stockpile
 go 1.15
 
 require (
-	github.com/google/go-cmp v0.5.2
 	go.mongodb.org/mongo-driver v1.4.2
 	google.golang.org/protobuf v1.25.0
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -0,0 +1,262 @@
+// Package logging wraps a db.Database, such as mongodb.MongoClient or mock.DB,
+// logging its operations as structured records
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// Record describes an operation of the database
+type Record struct {
+	Time       time.Time
+	Operation  string
+	Collection string
+	// Filter is the filter of the operation with its sensitive values redacted
+	Filter       map[string]interface{}
+	Options      *db.Options
+	WriteOptions *db.WriteOptions
+	Duration     time.Duration
+	// Count is the number of documents found, inserted or upserted, set
+	// with HasCount when the database knows it
+	Count    int
+	HasCount bool
+	Err      error
+	// Slow is set when the operation took at least the slow threshold
+	Slow bool
+}
+
+// Level is "error" when the operation failed, "warn" when it was slow and
+// "info" otherwise. db.ErrNotFound is a result rather than a failure
+func (r Record) Level() string {
+	switch {
+	case failed(r.Err):
+		return "error"
+	case r.Slow:
+		return "warn"
+	}
+	return "info"
+}
+
+func failed(err error) bool {
+	return err != nil && !errors.Is(err, db.ErrNotFound)
+}
+
+// Logger writes records, it must be safe for concurrent use
+type Logger interface {
+	Log(r Record)
+}
+
+// LoggerFunc is a function used as a Logger, such as one passing records to
+// the logging library of the application
+type LoggerFunc func(r Record)
+
+func (f LoggerFunc) Log(r Record) {
+	f(r)
+}
+
+// jsonRecord is the line written by JSONLogger
+type jsonRecord struct {
+	Time         time.Time              `json:"time"`
+	Level        string                 `json:"level"`
+	Operation    string                 `json:"operation"`
+	Collection   string                 `json:"collection,omitempty"`
+	Filter       map[string]interface{} `json:"filter,omitempty"`
+	Options      *jsonOptions           `json:"options,omitempty"`
+	WriteOptions *jsonWriteOptions      `json:"write_options,omitempty"`
+	DurationMS   float64                `json:"duration_ms"`
+	Count        *int                   `json:"count,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+	Slow         bool                   `json:"slow,omitempty"`
+}
+
+// jsonOptions are the read options of a jsonRecord
+type jsonOptions struct {
+	Limit          int64     `json:"limit,omitempty"`
+	Skip           int64     `json:"skip,omitempty"`
+	Sort           *jsonSort `json:"sort,omitempty"`
+	ScoreField     string    `json:"score_field,omitempty"`
+	ReadPreference string    `json:"read_preference,omitempty"`
+	ReadConcern    string    `json:"read_concern,omitempty"`
+}
+
+type jsonSort struct {
+	Key   string `json:"key"`
+	Order int    `json:"order"`
+}
+
+// jsonWriteOptions are the write options of a jsonRecord
+type jsonWriteOptions struct {
+	WriteConcern string  `json:"write_concern,omitempty"`
+	Journal      *bool   `json:"journal,omitempty"`
+	WTimeoutMS   float64 `json:"wtimeout_ms,omitempty"`
+}
+
+func toJSONOptions(o *db.Options) *jsonOptions {
+	if o == nil {
+		return nil
+	}
+	j := &jsonOptions{
+		Limit:          o.Limit,
+		Skip:           o.Skip,
+		ScoreField:     o.ScoreField,
+		ReadPreference: string(o.ReadPreference),
+		ReadConcern:    string(o.ReadConcern),
+	}
+	if o.Sort != nil {
+		j.Sort = &jsonSort{Key: o.Sort.Key, Order: o.Sort.Value}
+	}
+	return j
+}
+
+func toJSONWriteOptions(o *db.WriteOptions) *jsonWriteOptions {
+	if o == nil {
+		return nil
+	}
+	return &jsonWriteOptions{
+		WriteConcern: o.WriteConcern,
+		Journal:      o.Journal,
+		WTimeoutMS:   float64(o.WTimeout) / float64(time.Millisecond),
+	}
+}
+
+type jsonLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// JSONLogger writes every record to w as a line of JSON
+func JSONLogger(w io.Writer) Logger {
+	return &jsonLogger{enc: json.NewEncoder(w)}
+}
+
+func (l *jsonLogger) Log(r Record) {
+	line := jsonRecord{
+		Time:         r.Time,
+		Level:        r.Level(),
+		Operation:    r.Operation,
+		Collection:   r.Collection,
+		Filter:       r.Filter,
+		Options:      toJSONOptions(r.Options),
+		WriteOptions: toJSONWriteOptions(r.WriteOptions),
+		DurationMS:   float64(r.Duration) / float64(time.Millisecond),
+		Slow:         r.Slow,
+	}
+	if r.HasCount {
+		line.Count = &r.Count
+	}
+	if r.Err != nil {
+		line.Error = r.Err.Error()
+	}
+	l.mu.Lock()
+	_ = l.enc.Encode(line)
+	l.mu.Unlock()
+}
+
+// Options set which operations are logged and what is hidden from the records
+type Options struct {
+	slowThreshold time.Duration
+	sampleRate    float64
+	redactor      *db.Redactor
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+// CreateOptions creates options logging every operation, with the
+// db.DefaultRedactedFields of filters redacted
+func CreateOptions() *Options {
+	return &Options{
+		sampleRate: 1,
+		redactor:   db.CreateRedactor(),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetSlowThreshold marks the operations taking at least d as slow, slow
+// operations are always logged
+func (o *Options) SetSlowThreshold(d time.Duration) *Options {
+	o.slowThreshold = d
+	return o
+}
+
+// SetSampleRate logs the fraction of the operations which are neither slow
+// nor failed, 0 only logs slow and failed operations
+func (o *Options) SetSampleRate(rate float64) *Options {
+	if rate < 0 {
+		rate = 0
+	}
+	o.sampleRate = rate
+	return o
+}
+
+// SetRedactor sets the redactor applied to the filters of the records, nil
+// logs filters as they are
+func (o *Options) SetRedactor(r *db.Redactor) *Options {
+	o.redactor = r
+	return o
+}
+
+// sampled reports whether an operation which is neither slow nor failed is logged
+func (o *Options) sampled() bool {
+	if o.sampleRate >= 1 {
+		return true
+	}
+	if o.sampleRate <= 0 {
+		return false
+	}
+	o.randMu.Lock()
+	defer o.randMu.Unlock()
+	return o.rand.Float64() < o.sampleRate
+}
+
+// Middleware logs the operations passing through db.Wrap to the logger with
+// the options, CreateOptions when nil
+func Middleware(logger Logger, opts *Options) db.Middleware {
+	if opts == nil {
+		opts = CreateOptions()
+	}
+	return func(next db.Handler) db.Handler {
+		return func(op *db.Operation) error {
+			start := time.Now()
+			err := next(op)
+			duration := time.Since(start)
+
+			slow := opts.slowThreshold > 0 && duration >= opts.slowThreshold
+			if !slow && !failed(err) && !opts.sampled() {
+				return err
+			}
+			r := Record{
+				Time:         start,
+				Operation:    op.Method,
+				Collection:   op.Collection,
+				Options:      op.Options,
+				WriteOptions: op.WriteOptions,
+				Duration:     duration,
+				Count:        op.Count,
+				HasCount:     op.HasCount,
+				Err:          err,
+				Slow:         slow,
+			}
+			if opts.redactor != nil {
+				r.Filter = opts.redactor.Filter(op.Filter)
+			} else if op.Filter != nil {
+				r.Filter = *op.Filter
+			}
+			logger.Log(r)
+			return err
+		}
+	}
+}
+
+// Wrap logs the operations of the database to the logger with the options,
+// CreateOptions when nil
+func Wrap(database db.Database, logger Logger, opts *Options) db.Database {
+	return db.Wrap(database, Middleware(logger, opts))
+}
@@ -0,0 +1,169 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"github.com/sschwartz96/stockpile/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type user struct {
+	ID       string `json:"id" bson:"_id"`
+	Name     string `json:"name" bson:"name"`
+	Password string `json:"password" bson:"password"`
+}
+
+// recorder keeps the records logged
+type recorder struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (r *recorder) Log(rec Record) {
+	r.mu.Lock()
+	r.records = append(r.records, rec)
+	r.mu.Unlock()
+}
+
+func TestMiddleware(t *testing.T) {
+	m := mock.CreateDB()
+	if err := m.Insert("users", user{ID: "1", Name: "foo", Password: "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	m.Fail().Delete("users").Return(db.ErrNetwork)
+	m.Fail().FindAll("users").Delay(20 * time.Millisecond)
+
+	tests := []struct {
+		name string
+		opts *Options
+		call func(d db.Database) error
+		want []Record
+	}{
+		{
+			"every operation",
+			nil,
+			func(d db.Database) error {
+				var found user
+				return d.FindOne("users", &found, &db.Filter{"name": "foo", "password": "hunter2"}, db.CreateOptions().SetLimit(1))
+			},
+			[]Record{{Operation: "FindOne", Collection: "users", Filter: map[string]interface{}{"name": "foo", "password": db.Redacted}, Options: db.CreateOptions().SetLimit(1), Count: 1, HasCount: true}},
+		},
+		{
+			"write options",
+			nil,
+			func(d db.Database) error {
+				return d.Update("users", user{ID: "1", Name: "bar"}, &db.Filter{"_id": "1"}, db.CreateWriteOptions().SetMajority())
+			},
+			[]Record{{Operation: "Update", Collection: "users", Filter: map[string]interface{}{"_id": "1"}, WriteOptions: &db.WriteOptions{WriteConcern: "majority"}}},
+		},
+		{
+			"not found is not an error",
+			CreateOptions().SetSampleRate(0),
+			func(d db.Database) error {
+				return d.Update("users", user{ID: "2"}, &db.Filter{"_id": "2"})
+			},
+			nil,
+		},
+		{
+			"errors are always logged",
+			CreateOptions().SetSampleRate(0),
+			func(d db.Database) error {
+				return d.Delete("users", &db.Filter{"_id": "1"})
+			},
+			[]Record{{Operation: "Delete", Collection: "users", Filter: map[string]interface{}{"_id": "1"}, Err: db.ErrNetwork}},
+		},
+		{
+			"slow operations are always logged",
+			CreateOptions().SetSampleRate(0).SetSlowThreshold(10 * time.Millisecond).SetRedactor(db.CreateRedactor().SetRedactValues(true)),
+			func(d db.Database) error {
+				var found []user
+				if err := d.FindAll("users", &found, &db.Filter{"_id": "1"}, nil); err != nil {
+					return err
+				}
+				return d.Ping(nil)
+			},
+			[]Record{{Operation: "FindAll", Collection: "users", Filter: map[string]interface{}{"_id": "?"}, Count: 1, HasCount: true, Slow: true}},
+		},
+		{
+			"without redactor",
+			CreateOptions().SetRedactor(nil),
+			func(d db.Database) error {
+				var found []user
+				return d.Search("users", "foo", []string{"name"}, &found)
+			},
+			[]Record{{Operation: "Search", Collection: "users", Count: 0, HasCount: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			err := tt.call(Wrap(m, rec, tt.opts))
+			if len(tt.want) > 0 && !errors.Is(err, tt.want[0].Err) {
+				t.Errorf("error = %v, want %v", err, tt.want[0].Err)
+			}
+			if len(rec.records) != len(tt.want) {
+				t.Fatalf("records = %+v, want %+v", rec.records, tt.want)
+			}
+			for i, got := range rec.records {
+				if got.Time.IsZero() || got.Duration <= 0 {
+					t.Errorf("record has no time or duration: %+v", got)
+				}
+				got.Time, got.Duration = time.Time{}, 0
+				if !errors.Is(got.Err, tt.want[i].Err) {
+					t.Errorf("record error = %v, want %v", got.Err, tt.want[i].Err)
+				}
+				got.Err = tt.want[i].Err
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("record = %+v, want %+v", got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOptions_SampleRate(t *testing.T) {
+	opts := CreateOptions().SetSampleRate(0.25)
+	logged := 0
+	for i := 0; i < 10000; i++ {
+		if opts.sampled() {
+			logged++
+		}
+	}
+	if logged < 2000 || logged > 3000 {
+		t.Errorf("sampled %d of 10000 operations, want about 2500", logged)
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := JSONLogger(&buf)
+	at := time.Date(2020, 11, 3, 10, 0, 0, 0, time.UTC)
+	l.Log(Record{Time: at, Operation: "FindAll", Collection: "users", Filter: map[string]interface{}{"password": db.Redacted}, Duration: 1500 * time.Microsecond, Count: 2, HasCount: true, Slow: true})
+	l.Log(Record{Time: at, Operation: "Insert", Collection: "users", WriteOptions: db.CreateWriteOptions().SetJournal(true).SetWTimeout(time.Second), Err: db.ErrDuplicateKey})
+	id, _ := primitive.ObjectIDFromHex("5fa1c2d3e4f5a6b7c8d9e0f1")
+	filter := db.CreateRedactor().Filter(&db.Filter{"_id": id})
+	l.Log(Record{Time: at, Operation: "FindOne", Collection: "users", Filter: filter, Options: db.CreateOptions().SetSort("name", -1).SetReadPreference(db.ReadSecondary)})
+
+	want := []string{
+		`{"time":"2020-11-03T10:00:00Z","level":"warn","operation":"FindAll","collection":"users","filter":{"password":"[REDACTED]"},"duration_ms":1.5,"count":2,"slow":true}`,
+		`{"time":"2020-11-03T10:00:00Z","level":"error","operation":"Insert","collection":"users","write_options":{"journal":true,"wtimeout_ms":1000},"duration_ms":0,"error":"duplicate key"}`,
+		`{"time":"2020-11-03T10:00:00Z","level":"info","operation":"FindOne","collection":"users","filter":{"_id":"5fa1c2d3e4f5a6b7c8d9e0f1"},"options":{"sort":{"key":"name","order":-1},"read_preference":"secondary"},"duration_ms":0}`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSONLogger wrote\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, line := range got {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("invalid JSON: %v", line)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Package metrics wraps a db.Database, such as mongodb.MongoClient or mock.DB,
+// measuring the latency, errors and concurrency of its operations
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// Recorder receives the measurements of the operations, it must be safe for concurrent use
+type Recorder interface {
+	// InFlight adds delta to the number of running operations: 1 when one
+	// starts and -1 when it returns
+	InFlight(collection, operation string, delta int)
+	// Observe records a finished operation, err is nil when it succeeded.
+	// db.ErrNotFound is a result rather than a failure so it is observed as nil
+	Observe(collection, operation string, duration time.Duration, err error)
+}
+
+// ErrorKind classifies the error for the error counts: "duplicate_key",
+// "timeout", "network", "closed", "unknown_collection", "invalid_options",
+// "canceled" or "other". It is empty when the operation did not fail: the
+// error is nil or db.ErrNotFound
+func ErrorKind(err error) string {
+	switch {
+	case !failed(err):
+		return ""
+	case errors.Is(err, db.ErrDuplicateKey):
+		return "duplicate_key"
+	case errors.Is(err, db.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, db.ErrNetwork):
+		return "network"
+	case errors.Is(err, db.ErrClosed):
+		return "closed"
+	case errors.Is(err, db.ErrUnknownCollection):
+		return "unknown_collection"
+	case errors.Is(err, db.ErrInvalidOptions):
+		return "invalid_options"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "other"
+}
+
+func failed(err error) bool {
+	return err != nil && !errors.Is(err, db.ErrNotFound)
+}
+
+// Middleware records the operations passing through db.Wrap to the recorder
+func Middleware(r Recorder) db.Middleware {
+	return func(next db.Handler) db.Handler {
+		return func(op *db.Operation) error {
+			collection, operation := op.Collection, op.Method
+			r.InFlight(collection, operation, 1)
+			defer r.InFlight(collection, operation, -1)
+
+			start := time.Now()
+			err := next(op)
+			observed := err
+			if !failed(err) {
+				observed = nil
+			}
+			r.Observe(collection, operation, time.Since(start), observed)
+			return err
+		}
+	}
+}
+
+// Wrap records the operations of the database to the recorder
+func Wrap(database db.Database, r Recorder) db.Database {
+	return db.Wrap(database, Middleware(r))
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"github.com/sschwartz96/stockpile/mock"
+)
+
+type item struct {
+	ID   string `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+// recorder keeps the measurements as strings
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) InFlight(collection, operation string, delta int) {
+	r.mu.Lock()
+	r.events = append(r.events, fmt.Sprintf("in flight %s %s %+d", collection, operation, delta))
+	r.mu.Unlock()
+}
+
+func (r *recorder) Observe(collection, operation string, duration time.Duration, err error) {
+	r.mu.Lock()
+	r.events = append(r.events, fmt.Sprintf("observe %s %s %v", collection, operation, err))
+	r.mu.Unlock()
+}
+
+func TestMiddleware(t *testing.T) {
+	m := mock.CreateDB()
+	m.Fail().Delete("items").Return(db.ErrNetwork)
+	rec := &recorder{}
+	d := Wrap(m, rec)
+
+	if err := d.Insert("items", item{ID: "1", Name: "foo"}); err != nil {
+		t.Fatal("Insert() error:", err)
+	}
+	if err := d.Delete("items", nil); !errors.Is(err, db.ErrNetwork) {
+		t.Errorf("Delete() error = %v, want db.ErrNetwork", err)
+	}
+	if err := d.Update("items", item{ID: "2"}, &db.Filter{"_id": "2"}); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("Update() error = %v, want db.ErrNotFound", err)
+	}
+	want := []string{
+		"in flight items Insert +1",
+		"observe items Insert <nil>",
+		"in flight items Insert -1",
+		"in flight items Delete +1",
+		"observe items Delete database network error",
+		"in flight items Delete -1",
+		"in flight items Update +1",
+		"observe items Update <nil>",
+		"in flight items Update -1",
+	}
+	if !reflect.DeepEqual(rec.events, want) {
+		t.Errorf("events = %q, want %q", rec.events, want)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("update: %w", db.ErrNotFound), ""},
+		{db.ErrDuplicateKey, "duplicate_key"},
+		{db.ErrTimeout, "timeout"},
+		{context.DeadlineExceeded, "timeout"},
+		{db.ErrNetwork, "network"},
+		{&db.ClosedError{Database: "mock"}, "closed"},
+		{&db.UnknownCollectionError{Collection: "items"}, "unknown_collection"},
+		{db.ErrInvalidOptions, "invalid_options"},
+		{context.Canceled, "canceled"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorKind(tt.err); got != tt.want {
+			t.Errorf("ErrorKind(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestPrometheus_Write(t *testing.T) {
+	p := CreatePrometheus().SetNamespace("app").SetBuckets(0.1, 0.01)
+	p.InFlight("users", "FindAll", 1)
+	p.InFlight("users", "FindAll", 1)
+	p.InFlight("users", "FindAll", -1)
+	p.Observe("users", "FindAll", 5*time.Millisecond, nil)
+	p.Observe("users", "FindAll", 50*time.Millisecond, nil)
+	p.Observe("users", "FindAll", time.Second, db.ErrTimeout)
+	p.Observe("a\"b", "Insert", 10*time.Millisecond, db.ErrDuplicateKey)
+	p.Observe("a\"b", "Insert", 10*time.Millisecond, errors.New("boom"))
+	p.Observe("users", "FindOne", 10*time.Millisecond, db.ErrNotFound)
+
+	want := `# HELP app_operation_duration_seconds Latency of the database operations.
+# TYPE app_operation_duration_seconds histogram
+app_operation_duration_seconds_bucket{collection="a\"b",operation="Insert",le="0.01"} 2
+app_operation_duration_seconds_bucket{collection="a\"b",operation="Insert",le="0.1"} 2
+app_operation_duration_seconds_bucket{collection="a\"b",operation="Insert",le="+Inf"} 2
+app_operation_duration_seconds_sum{collection="a\"b",operation="Insert"} 0.02
+app_operation_duration_seconds_count{collection="a\"b",operation="Insert"} 2
+app_operation_duration_seconds_bucket{collection="users",operation="FindAll",le="0.01"} 1
+app_operation_duration_seconds_bucket{collection="users",operation="FindAll",le="0.1"} 2
+app_operation_duration_seconds_bucket{collection="users",operation="FindAll",le="+Inf"} 3
+app_operation_duration_seconds_sum{collection="users",operation="FindAll"} 1.055
+app_operation_duration_seconds_count{collection="users",operation="FindAll"} 3
+app_operation_duration_seconds_bucket{collection="users",operation="FindOne",le="0.01"} 1
+app_operation_duration_seconds_bucket{collection="users",operation="FindOne",le="0.1"} 1
+app_operation_duration_seconds_bucket{collection="users",operation="FindOne",le="+Inf"} 1
+app_operation_duration_seconds_sum{collection="users",operation="FindOne"} 0.01
+app_operation_duration_seconds_count{collection="users",operation="FindOne"} 1
+# HELP app_operation_errors_total Errors of the database operations by kind.
+# TYPE app_operation_errors_total counter
+app_operation_errors_total{collection="a\"b",operation="Insert",error="duplicate_key"} 1
+app_operation_errors_total{collection="a\"b",operation="Insert",error="other"} 1
+app_operation_errors_total{collection="users",operation="FindAll",error="timeout"} 1
+# HELP app_operations_in_flight Database operations running.
+# TYPE app_operations_in_flight gauge
+app_operations_in_flight{collection="users",operation="FindAll"} 1
+`
+	var buf strings.Builder
+	if err := p.Write(&buf); err != nil {
+		t.Fatal("Write() error:", err)
+	}
+	if buf.String() != want {
+		t.Errorf("Write() =\n%v\nwant\n%v", buf.String(), want)
+	}
+}
+
+func TestPrometheus_SetBuckets(t *testing.T) {
+	p := CreatePrometheus().SetNamespace("app")
+	p.Observe("users", "FindAll", 5*time.Millisecond, db.ErrTimeout)
+	p.SetBuckets(0.5, 0.01, 0.1)
+	p.Observe("users", "FindAll", 50*time.Millisecond, nil)
+
+	// latencies observed before are discarded, the errors are kept
+	want := `# HELP app_operation_duration_seconds Latency of the database operations.
+# TYPE app_operation_duration_seconds histogram
+app_operation_duration_seconds_bucket{collection="users",operation="FindAll",le="0.01"} 0
+app_operation_duration_seconds_bucket{collection="users",operation="FindAll",le="0.1"} 1
+app_operation_duration_seconds_bucket{collection="users",operation="FindAll",le="0.5"} 1
+app_operation_duration_seconds_bucket{collection="users",operation="FindAll",le="+Inf"} 1
+app_operation_duration_seconds_sum{collection="users",operation="FindAll"} 0.05
+app_operation_duration_seconds_count{collection="users",operation="FindAll"} 1
+# HELP app_operation_errors_total Errors of the database operations by kind.
+# TYPE app_operation_errors_total counter
+app_operation_errors_total{collection="users",operation="FindAll",error="timeout"} 1
+# HELP app_operations_in_flight Database operations running.
+# TYPE app_operations_in_flight gauge
+`
+	var buf strings.Builder
+	if err := p.Write(&buf); err != nil {
+		t.Fatal("Write() error:", err)
+	}
+	if buf.String() != want {
+		t.Errorf("Write() =\n%v\nwant\n%v", buf.String(), want)
+	}
+}
+
+func TestPrometheus_ServeHTTP(t *testing.T) {
+	p := CreatePrometheus()
+	d := Wrap(mock.CreateDB(), p)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = d.Insert("items", item{ID: fmt.Sprint(i)})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest(method, "/metrics", nil))
+		if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+			t.Errorf("%v Content-Type = %v", method, ct)
+		}
+		body := w.Body.String()
+		if method == http.MethodHead {
+			if body != "" {
+				t.Errorf("HEAD body = %q, want none", body)
+			}
+			continue
+		}
+		for _, line := range []string{
+			`stockpile_operation_duration_seconds_bucket{collection="items",operation="Insert",le="+Inf"} 10`,
+			`stockpile_operation_duration_seconds_count{collection="items",operation="Insert"} 10`,
+			`stockpile_operations_in_flight{collection="items",operation="Insert"} 0`,
+		} {
+			if !strings.Contains(body, line+"\n") {
+				t.Errorf("GET body does not contain %q:\n%v", line, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the latency histogram buckets
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// series identifies the metrics of an operation on a collection
+type series struct {
+	collection string
+	operation  string
+}
+
+// errorSeries identifies the error count of an operation by kind of error
+type errorSeries struct {
+	series
+	kind string
+}
+
+type histogram struct {
+	// counts are the observations per bucket, not cumulative
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Prometheus is a Recorder exposing the metrics in the Prometheus text
+// exposition format, so they are scraped without a Prometheus client:
+//
+//	<namespace>_operation_duration_seconds histogram of the latency
+//	<namespace>_operation_errors_total     counter of the errors by kind
+//	<namespace>_operations_in_flight       gauge of the running operations
+//
+// labelled by collection and operation
+type Prometheus struct {
+	namespace string
+	buckets   []float64
+
+	mu        sync.Mutex
+	durations map[series]*histogram
+	errors    map[errorSeries]uint64
+	inFlight  map[series]int64
+}
+
+// CreatePrometheus creates a recorder with the "stockpile" namespace and DefaultBuckets
+func CreatePrometheus() *Prometheus {
+	return &Prometheus{
+		namespace: "stockpile",
+		buckets:   DefaultBuckets,
+		durations: make(map[series]*histogram),
+		errors:    make(map[errorSeries]uint64),
+		inFlight:  make(map[series]int64),
+	}
+}
+
+// SetNamespace sets the prefix of the metric names
+func (p *Prometheus) SetNamespace(namespace string) *Prometheus {
+	p.namespace = namespace
+	return p
+}
+
+// SetBuckets sets the upper bounds in seconds of the latency histogram
+// buckets, the latencies observed before are discarded
+func (p *Prometheus) SetBuckets(buckets ...float64) *Prometheus {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buckets = buckets
+	p.durations = make(map[series]*histogram)
+	return p
+}
+
+func (p *Prometheus) InFlight(collection, operation string, delta int) {
+	p.mu.Lock()
+	p.inFlight[series{collection, operation}] += int64(delta)
+	p.mu.Unlock()
+}
+
+func (p *Prometheus) Observe(collection, operation string, duration time.Duration, err error) {
+	s := series{collection, operation}
+	seconds := duration.Seconds()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.durations[s]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		p.durations[s] = h
+	}
+	if i := sort.SearchFloat64s(p.buckets, seconds); i < len(p.buckets) {
+		h.counts[i]++
+	}
+	h.sum += seconds
+	h.count++
+	if kind := ErrorKind(err); kind != "" {
+		p.errors[errorSeries{s, kind}]++
+	}
+}
+
+// Write writes the metrics in the Prometheus text exposition format
+func (p *Prometheus) Write(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	bw := bufio.NewWriter(w)
+
+	name := p.namespace + "_operation_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s Latency of the database operations.\n# TYPE %s histogram\n", name, name)
+	for _, s := range sortedSeries(p.durations) {
+		h := p.durations[s]
+		labels := s.labels()
+		var cumulative uint64
+		for i, le := range p.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(bw, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(bw, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(bw, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+		fmt.Fprintf(bw, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+
+	name = p.namespace + "_operation_errors_total"
+	fmt.Fprintf(bw, "# HELP %s Errors of the database operations by kind.\n# TYPE %s counter\n", name, name)
+	errs := make([]errorSeries, 0, len(p.errors))
+	for s := range p.errors {
+		errs = append(errs, s)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].series != errs[j].series {
+			return errs[i].series.less(errs[j].series)
+		}
+		return errs[i].kind < errs[j].kind
+	})
+	for _, s := range errs {
+		fmt.Fprintf(bw, "%s{%s,error=\"%s\"} %d\n", name, s.labels(), escapeLabel(s.kind), p.errors[s])
+	}
+
+	name = p.namespace + "_operations_in_flight"
+	fmt.Fprintf(bw, "# HELP %s Database operations running.\n# TYPE %s gauge\n", name, name)
+	for _, s := range sortedSeries(p.inFlight) {
+		fmt.Fprintf(bw, "%s{%s} %d\n", name, s.labels(), p.inFlight[s])
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	_ = p.Write(w)
+}
+
+func (s series) labels() string {
+	return fmt.Sprintf("collection=\"%s\",operation=\"%s\"", escapeLabel(s.collection), escapeLabel(s.operation))
+}
+
+func (s series) less(o series) bool {
+	if s.collection != o.collection {
+		return s.collection < o.collection
+	}
+	return s.operation < o.operation
+}
+
+// sortedSeries returns the keys of the map, which is keyed by series, in order
+func sortedSeries(m interface{}) []series {
+	var keys []series
+	switch m := m.(type) {
+	case map[series]*histogram:
+		for s := range m {
+			keys = append(keys, s)
+		}
+	case map[series]int64:
+		for s := range m {
+			keys = append(keys, s)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
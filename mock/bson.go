@@ -0,0 +1,86 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+)
+
+// CreateBSONDB creates a DB which stores every document as BSON.
+// Objects are marshalled with registry when written and unmarshalled into the
+// caller's type when read, so custom codecs, MarshalBSON/UnmarshalBSON,
+// bson tags and type coercions behave the way they do against MongoDB.
+// A nil registry uses bson.DefaultRegistry, the same default as mongodb.MongoClient
+func CreateBSONDB(registry *bsoncodec.Registry) *DB {
+	if registry == nil {
+		registry = bson.DefaultRegistry
+	}
+	return &DB{collectionMap: make(map[string]*[]interface{}), registry: registry}
+}
+
+// encode prepares object to be stored, dereferencing pointers and
+// marshalling into bson.Raw when the DB is in BSON mode
+func (d *DB) encode(object interface{}) (interface{}, error) {
+	objVal := reflect.ValueOf(object)
+	if objVal.Kind() == reflect.Ptr {
+		if objVal.IsNil() {
+			return nil, errors.New("object is nil")
+		}
+		objVal = objVal.Elem()
+	}
+	if d.registry == nil {
+		return objVal.Interface(), nil
+	}
+	data, err := bson.MarshalWithRegistry(d.registry, object)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling object: %v", err)
+	}
+	return bson.Raw(data), nil
+}
+
+// matches reports whether the stored data satisfies the filter
+func (d *DB) matches(data interface{}, filter *db.Filter) bool {
+	if raw, ok := data.(bson.Raw); ok {
+		return d.compareRawToFilter(raw, filter)
+	}
+	return d.compareInterfaceToFilter(data, filter)
+}
+
+// compareRawToFilter compares the raw document to the filter. Filter values
+// are marshalled with the registry first, so they are compared the same way
+// MongoDB would compare them
+func (d *DB) compareRawToFilter(raw bson.Raw, filter *db.Filter) bool {
+	bsonFilter := make(db.Filter, len(*filter))
+	for filterKey, filterV := range *filter {
+		t, data, err := bson.MarshalValueWithRegistry(d.registry, filterV)
+		if err != nil {
+			return false
+		}
+		var v interface{}
+		if err := (bson.RawValue{Type: t, Value: data}).UnmarshalWithRegistry(d.registry, &v); err != nil {
+			return false
+		}
+		bsonFilter[filterKey] = v
+	}
+	return d.compareInterfaceToFilter(raw, &bsonFilter)
+}
+
+// lookupRaw finds the element within raw whose key matches name,
+// ignoring case and underscores
+func lookupRaw(raw bson.Raw, name string) (bson.RawValue, bool) {
+	elements, err := raw.Elements()
+	if err != nil {
+		return bson.RawValue{}, false
+	}
+	match := matchFieldFunc(name)
+	for _, element := range elements {
+		if match(element.Key()) {
+			return element.Value(), true
+		}
+	}
+	return bson.RawValue{}, false
+}
@@ -0,0 +1,177 @@
+package mock
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+type bsonTestObj struct {
+	ID      string `bson:"_id"`
+	Name    string `bson:"user_name"`
+	Count   int32  `bson:"count"`
+	Comment string `bson:"comment,omitempty"`
+	Code    upperCode
+}
+
+// upperCode is stored upper case and read back lower case
+type upperCode string
+
+func (u upperCode) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(strings.ToUpper(string(u)))
+}
+
+func (u *upperCode) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var s string
+	if err := (bson.RawValue{Type: t, Value: data}).Unmarshal(&s); err != nil {
+		return err
+	}
+	*u = upperCode(strings.ToLower(s))
+	return nil
+}
+
+func TestCreateBSONDB(t *testing.T) {
+	d := CreateBSONDB(nil)
+	if d.registry != bson.DefaultRegistry {
+		t.Errorf("CreateBSONDB() registry = %v, want bson.DefaultRegistry", d.registry)
+	}
+	if err := d.Open(context.Background()); err != nil {
+		t.Fatal("error opening db:", err)
+	}
+	if d.registry == nil {
+		t.Errorf("DB.Open() cleared the registry")
+	}
+}
+
+func TestDB_BSONMode(t *testing.T) {
+	t.Parallel()
+	d := CreateBSONDB(nil)
+	obj1 := bsonTestObj{ID: "1", Name: "first", Count: 1, Code: "abc"}
+	obj2 := bsonTestObj{ID: "2", Name: "second", Count: 2, Comment: "hello world", Code: "def"}
+	for _, obj := range []interface{}{obj1, &obj2} {
+		if err := d.Insert("col", obj); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+
+	// documents must be stored as marshalled bson
+	stored, ok := (*d.collectionMap["col"])[0].(bson.Raw)
+	if !ok {
+		t.Fatalf("stored document is %T, want bson.Raw", (*d.collectionMap["col"])[0])
+	}
+	if _, err := stored.LookupErr("comment"); err == nil {
+		t.Errorf("omitempty field was stored: %v", stored)
+	}
+	if code := stored.Lookup("code").StringValue(); code != "ABC" {
+		t.Errorf("custom marshaller not used, code = %v", code)
+	}
+
+	t.Run("FindOne()_int_coercion", func(t *testing.T) {
+		var got bsonTestObj
+		if err := d.FindOne("col", &got, &db.Filter{"count": int64(2)}, nil); err != nil {
+			t.Fatal("DB.FindOne() error:", err)
+		}
+		if !reflect.DeepEqual(got, obj2) {
+			t.Errorf("DB.FindOne() = %v, want %v", got, obj2)
+		}
+	})
+
+	t.Run("FindAll()_ptr_slice_sort", func(t *testing.T) {
+		var got []*bsonTestObj
+		if err := d.FindAll("col", &got, nil, db.CreateOptions().SetSort("count", -1)); err != nil {
+			t.Fatal("DB.FindAll() error:", err)
+		}
+		if !sliceDeepEqual(&got, &[]bsonTestObj{obj2, obj1}) {
+			t.Errorf("DB.FindAll() = %v, want %v", got, []bsonTestObj{obj2, obj1})
+		}
+	})
+
+	t.Run("FindAll()_tag_name", func(t *testing.T) {
+		var got []bsonTestObj
+		if err := d.FindAll("col", &got, &db.Filter{"user_name": "first"}, nil); err != nil {
+			t.Fatal("DB.FindAll() error:", err)
+		}
+		if !sliceDeepEqual(&got, &[]bsonTestObj{obj1}) {
+			t.Errorf("DB.FindAll() = %v, want %v", got, []bsonTestObj{obj1})
+		}
+	})
+
+	t.Run("Search()", func(t *testing.T) {
+		var got []bsonTestObj
+		if err := d.Search("col", "world", []string{"comment", "count"}, &got); err != nil {
+			t.Fatal("DB.Search() error:", err)
+		}
+		if !sliceDeepEqual(&got, &[]bsonTestObj{obj2}) {
+			t.Errorf("DB.Search() = %v, want %v", got, []bsonTestObj{obj2})
+		}
+	})
+}
+
+func TestDB_BSONMode_Update_Delete(t *testing.T) {
+	t.Parallel()
+	d := CreateBSONDB(nil)
+	if err := d.Insert("col", bsonTestObj{ID: "1", Name: "first", Count: 1}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+
+	updated := bsonTestObj{ID: "1", Name: "updated", Count: 10}
+	if err := d.Update("col", updated, &db.Filter{"_id": "1"}); err != nil {
+		t.Fatal("DB.Update() error:", err)
+	}
+	var got bsonTestObj
+	if err := d.FindOne("col", &got, &db.Filter{"_id": "1"}, nil); err != nil {
+		t.Fatal("DB.FindOne() error:", err)
+	}
+	if !reflect.DeepEqual(got, updated) {
+		t.Errorf("DB.FindOne() = %v, want %v", got, updated)
+	}
+
+	if err := d.Upsert("col", bsonTestObj{ID: "2", Name: "upserted"}, &db.Filter{"_id": "2"}); err != nil {
+		t.Fatal("DB.Upsert() error:", err)
+	}
+	if err := d.Delete("col", &db.Filter{"count": 10.0}); err != nil {
+		t.Fatal("DB.Delete() error:", err)
+	}
+	var all []bsonTestObj
+	if err := d.FindAll("col", &all, nil, nil); err != nil {
+		t.Fatal("DB.FindAll() error:", err)
+	}
+	if !sliceDeepEqual(&all, &[]bsonTestObj{{ID: "2", Name: "upserted"}}) {
+		t.Errorf("DB.FindAll() = %v, want only the upserted object", all)
+	}
+}
+
+func TestDB_compareRawToFilter(t *testing.T) {
+	d := CreateBSONDB(nil)
+	raw, err := bson.Marshal(bsonTestObj{ID: "1", Name: "foo", Count: 5, Code: "abc"})
+	if err != nil {
+		t.Fatal("error marshalling:", err)
+	}
+	tests := []struct {
+		name   string
+		filter *db.Filter
+		want   bool
+	}{
+		{"int32_int64", &db.Filter{"count": int64(5)}, true},
+		{"int_double", &db.Filter{"count": 5.0}, true},
+		{"double_fraction", &db.Filter{"count": 5.5}, false},
+		{"string", &db.Filter{"user_name": "foo"}, true},
+		{"string_int", &db.Filter{"user_name": 5}, false},
+		{"custom_marshaller", &db.Filter{"code": upperCode("abc")}, true},
+		{"raw_value", &db.Filter{"code": "abc"}, false},
+		{"operator", &db.Filter{"count": bson.M{"$gt": 4, "$lt": int32(6)}}, true},
+		{"missing", &db.Filter{"missing": "foo"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.compareRawToFilter(raw, tt.filter); got != tt.want {
+				t.Errorf("DB.compareRawToFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
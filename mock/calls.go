@@ -0,0 +1,328 @@
+package mock
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// Call is a single call made to a method of DB
+type Call struct {
+	Method     string
+	Collection string
+	Filter     *db.Filter
+	Options    *db.Options
+	// WriteOptions are the write options passed to a write method merged into one
+	WriteOptions *db.WriteOptions
+	// Object is the object or slice passed to the method
+	Object interface{}
+	// Search and Fields are the arguments of Search and SearchWithOptions
+	Search string
+	Fields []string
+}
+
+func (c Call) String() string {
+	s := c.Method + "("
+	if c.Collection != "" {
+		s += fmt.Sprintf("%q", c.Collection)
+	}
+	if c.Search != "" {
+		s += fmt.Sprintf(", search: %q", c.Search)
+	}
+	if c.Fields != nil {
+		s += fmt.Sprintf(", fields: %q", c.Fields)
+	}
+	if c.Filter != nil {
+		s += fmt.Sprintf(", filter: %v", *c.Filter)
+	}
+	if c.Options != nil {
+		s += fmt.Sprintf(", options: %+v", *c.Options)
+	}
+	if c.WriteOptions != nil {
+		s += fmt.Sprintf(", write options: %+v", *c.WriteOptions)
+	}
+	if c.Object != nil {
+		s += fmt.Sprintf(", object: %+v", c.Object)
+	}
+	return s + ")"
+}
+
+// record appends the call to the recorded calls of the DB and returns
+// a *db.ClosedError when the DB is closed or the error of the faults
+// injected into the call
+func (d *DB) record(call Call) error {
+	d.callsMu.Lock()
+	d.calls = append(d.calls, call)
+	d.callsMu.Unlock()
+	if call.Method != "Open" && call.Method != "Close" && d.isClosed() {
+		return fmt.Errorf("mock.DB.%v() error: %w", call.Method, &db.ClosedError{Database: "mock"})
+	}
+	return d.fault(call)
+}
+
+// checkClosed returns a *db.ClosedError when the DB is closed. The caller
+// must hold the lock of the DB, so Close can not run before it is done
+func (d *DB) checkClosed(method string) error {
+	if d.closed {
+		return fmt.Errorf("mock.DB.%v() error: %w", method, &db.ClosedError{Database: "mock"})
+	}
+	return nil
+}
+
+func (d *DB) isClosed() bool {
+	d.RLock()
+	defer d.RUnlock()
+	return d.closed
+}
+
+// Calls returns every call made to the DB in order
+func (d *DB) Calls() []Call {
+	d.callsMu.Lock()
+	defer d.callsMu.Unlock()
+	return append([]Call(nil), d.calls...)
+}
+
+// ResetCalls clears the recorded calls and the expectations of the DB
+func (d *DB) ResetCalls() {
+	d.callsMu.Lock()
+	defer d.callsMu.Unlock()
+	d.calls = nil
+	d.expectations = nil
+}
+
+// TestingT is the part of *testing.T used to report unmet expectations
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Expectation describes calls the DB is expected to receive,
+// it is created with DB.Expect and checked with DB.Verify
+type Expectation struct {
+	// mu is the callsMu of the DB, held while the expectation is verified
+	mu *sync.Mutex
+
+	method     string
+	collection string
+	filter     *db.Filter
+	options    *db.Options
+	writeOpts  *db.WriteOptions
+	object     interface{}
+	search     string
+	fields     []string
+	hasFilter  bool
+	hasOptions bool
+	hasWrite   bool
+	hasObject  bool
+	hasSearch  bool
+	hasFields  bool
+	// times is the expected number of calls, any number above zero when negative
+	times int
+}
+
+// Expect adds an expectation to the DB, the method it expects is set
+// by calling one of the methods of Expectation named after DB's methods
+func (d *DB) Expect() *Expectation {
+	e := &Expectation{mu: &d.callsMu, times: -1}
+	d.callsMu.Lock()
+	defer d.callsMu.Unlock()
+	d.expectations = append(d.expectations, e)
+	return e
+}
+
+// set changes the expectation under the lock of the DB, as it may be
+// verified concurrently
+func (e *Expectation) set(change func()) *Expectation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	change()
+	return e
+}
+
+func (e *Expectation) call(method, collection string) *Expectation {
+	return e.set(func() {
+		e.method = method
+		e.collection = collection
+	})
+}
+
+func (e *Expectation) Insert(collection string) *Expectation {
+	return e.call("Insert", collection)
+}
+
+func (e *Expectation) FindOne(collection string) *Expectation {
+	return e.call("FindOne", collection)
+}
+
+func (e *Expectation) FindAll(collection string) *Expectation {
+	return e.call("FindAll", collection)
+}
+
+func (e *Expectation) Update(collection string) *Expectation {
+	return e.call("Update", collection)
+}
+
+func (e *Expectation) Upsert(collection string) *Expectation {
+	return e.call("Upsert", collection)
+}
+
+func (e *Expectation) Delete(collection string) *Expectation {
+	return e.call("Delete", collection)
+}
+
+func (e *Expectation) Search(collection string) *Expectation {
+	return e.call("Search", collection)
+}
+
+func (e *Expectation) SearchWithOptions(collection string) *Expectation {
+	return e.call("SearchWithOptions", collection)
+}
+
+func (e *Expectation) Watch(collection string) *Expectation {
+	return e.call("Watch", collection)
+}
+
+func (e *Expectation) Ping() *Expectation {
+	return e.call("Ping", "")
+}
+
+func (e *Expectation) Drop(collection string) *Expectation {
+	return e.call("Drop", collection)
+}
+
+func (e *Expectation) Reset(collection string) *Expectation {
+	return e.call("Reset", collection)
+}
+
+// WithFilter only matches calls made with an equal filter
+func (e *Expectation) WithFilter(filter *db.Filter) *Expectation {
+	return e.set(func() {
+		e.filter = filter
+		e.hasFilter = true
+	})
+}
+
+// WithOptions only matches calls made with equal options
+func (e *Expectation) WithOptions(opts *db.Options) *Expectation {
+	return e.set(func() {
+		e.options = opts
+		e.hasOptions = true
+	})
+}
+
+// WithWriteOptions only matches calls made with equal write options,
+// the write options of a call are merged with db.MergeWriteOptions
+func (e *Expectation) WithWriteOptions(opts *db.WriteOptions) *Expectation {
+	return e.set(func() {
+		e.writeOpts = opts
+		e.hasWrite = true
+	})
+}
+
+// WithObject only matches calls made with an equal object
+func (e *Expectation) WithObject(object interface{}) *Expectation {
+	return e.set(func() {
+		e.object = object
+		e.hasObject = true
+	})
+}
+
+// WithSearch only matches calls searching for the same text
+func (e *Expectation) WithSearch(search string) *Expectation {
+	return e.set(func() {
+		e.search = search
+		e.hasSearch = true
+	})
+}
+
+// WithFields only matches calls searching the same fields in the same order
+func (e *Expectation) WithFields(fields ...string) *Expectation {
+	return e.set(func() {
+		e.fields = fields
+		e.hasFields = true
+	})
+}
+
+// Times sets the exact number of matching calls expected,
+// by default the method is expected to be called at least once
+func (e *Expectation) Times(n int) *Expectation {
+	return e.set(func() { e.times = n })
+}
+
+// Never expects the method not to be called
+func (e *Expectation) Never() *Expectation {
+	return e.Times(0)
+}
+
+// matches reports whether the call meets the expectation
+func (e *Expectation) matches(c Call) bool {
+	if c.Method != e.method || c.Collection != e.collection {
+		return false
+	}
+	if e.hasFilter && !reflect.DeepEqual(c.Filter, e.filter) {
+		return false
+	}
+	if e.hasOptions && !reflect.DeepEqual(c.Options, e.options) {
+		return false
+	}
+	if e.hasWrite && !reflect.DeepEqual(c.WriteOptions, e.writeOpts) {
+		return false
+	}
+	if e.hasSearch && c.Search != e.search {
+		return false
+	}
+	if e.hasFields && !equalFields(c.Fields, e.fields) {
+		return false
+	}
+	return !e.hasObject || reflect.DeepEqual(c.Object, e.object)
+}
+
+// equalFields reports whether both lists hold the same fields in the same
+// order, a nil list is equal to an empty one
+func equalFields(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) String() string {
+	c := Call{Method: e.method, Collection: e.collection, Filter: e.filter, Options: e.options, WriteOptions: e.writeOpts, Object: e.object, Search: e.search, Fields: e.fields}
+	return c.String()
+}
+
+// Verify reports an error to t for every expectation which was not met
+func (d *DB) Verify(t TestingT) {
+	t.Helper()
+	d.callsMu.Lock()
+	defer d.callsMu.Unlock()
+	for _, e := range d.expectations {
+		n := 0
+		for _, c := range d.calls {
+			if e.matches(c) {
+				n++
+			}
+		}
+		if (e.times < 0 && n > 0) || n == e.times {
+			continue
+		}
+		want := "at least once"
+		if e.times >= 0 {
+			want = fmt.Sprintf("%d time(s)", e.times)
+		}
+		calls := make([]string, len(d.calls))
+		for i, c := range d.calls {
+			calls[i] = c.String()
+		}
+		t.Errorf("mock.DB expected %v to be called %v, was called %d time(s)\ncalls:\n\t%v",
+			e, want, n, strings.Join(calls, "\n\t"))
+	}
+}
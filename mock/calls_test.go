@@ -0,0 +1,158 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// fakeT records the errors reported by DB.Verify
+type fakeT struct {
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestDB_Calls(t *testing.T) {
+	d := CreateDB()
+	obj := testObj{Name: "foo"}
+	filter := &db.Filter{"name": "foo"}
+	opts := db.CreateOptions().SetLimit(1)
+	var found []testObj
+
+	_ = d.Insert("users", obj)
+	_ = d.FindAll("users", &found, filter, opts)
+	_ = d.Upsert("users", obj, filter)
+	_ = d.Search("users", "foo", []string{"name"}, &found)
+	_ = d.Delete("users", &db.Filter{"name": "bar"})
+
+	want := []Call{
+		{Method: "Insert", Collection: "users", Object: obj},
+		{Method: "FindAll", Collection: "users", Filter: filter, Options: opts, Object: &found},
+		{Method: "Upsert", Collection: "users", Filter: filter, Object: obj},
+		{Method: "Search", Collection: "users", Search: "foo", Fields: []string{"name"}, Object: &found},
+		{Method: "Delete", Collection: "users", Filter: &db.Filter{"name": "bar"}},
+	}
+	if got := d.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("DB.Calls() = %v, want %v", got, want)
+	}
+
+	d.ResetCalls()
+	if got := d.Calls(); len(got) != 0 {
+		t.Errorf("DB.Calls() after reset = %v", got)
+	}
+}
+
+func TestDB_Verify(t *testing.T) {
+	filter := &db.Filter{"name": "foo"}
+	tests := []struct {
+		name       string
+		expect     func(e *Expectation)
+		wantErrors int
+	}{
+		{"called", func(e *Expectation) { e.Update("users") }, 0},
+		{"times", func(e *Expectation) { e.Update("users").Times(2) }, 0},
+		{"wrong_times", func(e *Expectation) { e.Update("users").Times(1) }, 1},
+		{"filter", func(e *Expectation) { e.Update("users").WithFilter(&db.Filter{"name": "foo"}).Times(1) }, 0},
+		{"wrong_filter", func(e *Expectation) { e.Update("users").WithFilter(&db.Filter{"name": "bar"}) }, 1},
+		{"object", func(e *Expectation) { e.Update("users").WithObject(testObj{Value: 2}) }, 0},
+		{"options", func(e *Expectation) { e.FindOne("users").WithOptions(nil).Times(1) }, 0},
+		{"wrong_collection", func(e *Expectation) { e.Update("posts") }, 1},
+		{"never", func(e *Expectation) { e.Delete("users").Never() }, 0},
+		{"never_called", func(e *Expectation) { e.Insert("users").Never() }, 1},
+		{"search", func(e *Expectation) { e.Search("users").WithSearch("foo").WithFields("name", "desc").Times(1) }, 0},
+		{"wrong_search", func(e *Expectation) { e.Search("users").WithSearch("bar") }, 1},
+		{"wrong_fields", func(e *Expectation) { e.Search("users").WithFields("name") }, 1},
+		{"search_with_options", func(e *Expectation) { e.SearchWithOptions("users").WithSearch("foo").WithFilter(filter).Times(1) }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := CreateDB()
+			tt.expect(d.Expect())
+
+			var obj testObj
+			_ = d.Insert("users", testObj{Name: "foo"})
+			_ = d.Update("users", testObj{Value: 1}, filter)
+			_ = d.Update("users", testObj{Value: 2}, &db.Filter{"value": 1})
+			_ = d.FindOne("users", &obj, filter, nil)
+			var found []testObj
+			_ = d.Search("users", "foo", []string{"name", "desc"}, &found)
+			_ = d.SearchWithOptions("users", "foo", []string{"name"}, &found, filter, nil)
+
+			ft := &fakeT{}
+			d.Verify(ft)
+			if len(ft.errors) != tt.wantErrors {
+				t.Errorf("DB.Verify() errors = %v, want %d", ft.errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestDB_ConsistencyOptions(t *testing.T) {
+	d := CreateDB()
+	obj := testObj{Name: "foo"}
+	filter := &db.Filter{"name": "foo"}
+	majority := db.CreateWriteOptions().SetMajority()
+	journaled := db.CreateWriteOptions().SetJournal(true)
+
+	if err := d.Insert("payments", obj, majority, journaled); err != nil {
+		t.Fatal("DB.Insert() error:", err)
+	}
+	d.Expect().Insert("payments").WithWriteOptions(db.CreateWriteOptions().SetMajority().SetJournal(true)).Times(1)
+	d.Expect().Update("payments").WithWriteOptions(nil).Never()
+	d.Verify(t)
+
+	secondary := db.CreateOptions().SetReadPreference(db.ReadSecondary)
+	var found []testObj
+	if err := d.FindAll("payments", &found, filter, secondary); err != nil || len(found) != 1 {
+		t.Fatalf("DB.FindAll() = %v, %v", found, err)
+	}
+
+	// invalid options are recorded and rejected before the operation is made
+	invalid := db.CreateWriteOptions().SetWriteConcern("0").SetJournal(true)
+	if err := d.Update("payments", testObj{Name: "foo", Value: 1}, filter, invalid); !errors.Is(err, db.ErrInvalidOptions) {
+		t.Errorf("DB.Update() error = %v, want db.ErrInvalidOptions", err)
+	}
+	if err := d.Delete("payments", filter, invalid); !errors.Is(err, db.ErrInvalidOptions) {
+		t.Errorf("DB.Delete() error = %v, want db.ErrInvalidOptions", err)
+	}
+	var one testObj
+	if err := d.FindOne("payments", &one, filter, db.CreateOptions().SetReadConcern("strong")); !errors.Is(err, db.ErrInvalidOptions) {
+		t.Errorf("DB.FindOne() error = %v, want db.ErrInvalidOptions", err)
+	}
+	if err := d.FindOne("payments", &one, filter, nil); err != nil || one.Value != 0 {
+		t.Errorf("DB.FindOne() = %+v, %v, want the document left unchanged", one, err)
+	}
+
+	calls := d.Calls()
+	if got := calls[2].WriteOptions; !reflect.DeepEqual(got, invalid) {
+		t.Errorf("DB.Calls() update write options = %+v, want %+v", got, invalid)
+	}
+}
+
+func TestDB_Expect_Concurrent(t *testing.T) {
+	// expectations are built while the DB is used and verified, run with -race
+	d := CreateDB()
+	e := d.Expect()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_ = d.Insert("users", testObj{Name: "foo"})
+			d.Verify(&fakeT{})
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		e.Insert("users").WithFilter(nil).WithObject(testObj{Name: "foo"}).WithSearch("").WithFields().Times(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,337 @@
+package mock
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// type ranks follow the MongoDB BSON comparison order,
+// values of a lower rank are always less than values of a higher rank
+const (
+	rankMinKey = iota
+	rankNull
+	rankNumber
+	rankString
+	rankObject
+	rankArray
+	rankBinary
+	rankObjectID
+	rankBool
+	rankDate
+	rankTimestamp
+	rankRegex
+	rankOther
+	rankMaxKey
+)
+
+var timestampType = reflect.TypeOf(&timestamppb.Timestamp{})
+
+// element is a single key value pair of a document
+type element struct {
+	key string
+	val reflect.Value
+}
+
+// typeRank returns the rank of the value within the BSON comparison order.
+// Invalid values, which are used for missing fields, and nil pointers rank as null
+func typeRank(v reflect.Value) int {
+	v, ok := concreteValue(v)
+	if !ok {
+		return rankNull
+	}
+	if v.Type() == timestampType {
+		if v.IsNil() {
+			return rankNull
+		}
+		return rankDate
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return rankNull
+		}
+		return typeRank(v.Elem())
+	}
+
+	switch v.Interface().(type) {
+	case primitive.MinKey:
+		return rankMinKey
+	case primitive.MaxKey:
+		return rankMaxKey
+	case primitive.Null, primitive.Undefined:
+		return rankNull
+	case primitive.Decimal128:
+		return rankNumber
+	case primitive.Binary:
+		return rankBinary
+	case primitive.ObjectID:
+		return rankObjectID
+	case time.Time, primitive.DateTime:
+		return rankDate
+	case primitive.Timestamp:
+		return rankTimestamp
+	case primitive.Regex:
+		return rankRegex
+	case primitive.D:
+		return rankObject
+	case primitive.JavaScript, primitive.CodeWithScope, primitive.DBPointer:
+		return rankOther
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return rankNumber
+	case reflect.String:
+		return rankString
+	case reflect.Bool:
+		return rankBool
+	case reflect.Map, reflect.Struct:
+		return rankObject
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return rankNull
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return rankBinary
+		}
+		return rankArray
+	}
+	return rankOther
+}
+
+// compareValues compares a and b using the MongoDB BSON comparison order.
+// It returns -1 if a < b, 0 if a == b and 1 if a > b
+func compareValues(a, b reflect.Value) int {
+	aRank, bRank := typeRank(a), typeRank(b)
+	if aRank != bRank {
+		return compareInts(int64(aRank), int64(bRank))
+	}
+	a, b = indirectValue(a), indirectValue(b)
+
+	switch aRank {
+	case rankNumber:
+		return compareNumbers(a, b)
+	case rankString:
+		return strings.Compare(a.String(), b.String())
+	case rankObject:
+		return compareDocuments(documentElements(a), documentElements(b))
+	case rankArray:
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			if c := compareValues(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return compareInts(int64(a.Len()), int64(b.Len()))
+	case rankBinary:
+		aSub, aData := binaryValue(a)
+		bSub, bData := binaryValue(b)
+		if len(aData) != len(bData) {
+			return compareInts(int64(len(aData)), int64(len(bData)))
+		}
+		if aSub != bSub {
+			return compareInts(int64(aSub), int64(bSub))
+		}
+		return bytes.Compare(aData, bData)
+	case rankObjectID:
+		aID, bID := a.Interface().(primitive.ObjectID), b.Interface().(primitive.ObjectID)
+		return bytes.Compare(aID[:], bID[:])
+	case rankBool:
+		if a.Bool() == b.Bool() {
+			return 0
+		}
+		if b.Bool() {
+			return -1
+		}
+		return 1
+	case rankDate:
+		aTime, bTime := timeValue(a), timeValue(b)
+		if aTime.Before(bTime) {
+			return -1
+		}
+		if aTime.After(bTime) {
+			return 1
+		}
+		return 0
+	case rankTimestamp:
+		aStamp, bStamp := a.Interface().(primitive.Timestamp), b.Interface().(primitive.Timestamp)
+		if aStamp.T != bStamp.T {
+			return compareInts(int64(aStamp.T), int64(bStamp.T))
+		}
+		return compareInts(int64(aStamp.I), int64(bStamp.I))
+	case rankRegex:
+		aRegex, bRegex := a.Interface().(primitive.Regex), b.Interface().(primitive.Regex)
+		if c := strings.Compare(aRegex.Pattern, bRegex.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(aRegex.Options, bRegex.Options)
+	}
+	return 0
+}
+
+// indirectValue unwraps interfaces and non nil pointers, except timestamps
+// which are compared as dates
+func indirectValue(v reflect.Value) reflect.Value {
+	v, _ = concreteValue(v)
+	for v.Kind() == reflect.Ptr && !v.IsNil() && v.Type() != timestampType {
+		v, _ = concreteValue(v.Elem())
+	}
+	return v
+}
+
+func compareInts(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// compareNumbers compares numbers of any type by value, NaN is less than
+// every other number as it is in MongoDB
+func compareNumbers(a, b reflect.Value) int {
+	aNum, aNaN := numberValue(a)
+	bNum, bNaN := numberValue(b)
+	switch {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return -1
+	case bNaN:
+		return 1
+	}
+	return aNum.Cmp(bNum)
+}
+
+// numberValue returns the exact value of the number as a big.Float,
+// the bool is true when the number is NaN
+func numberValue(v reflect.Value) (*big.Float, bool) {
+	f := new(big.Float).SetPrec(256)
+	if d, ok := v.Interface().(primitive.Decimal128); ok {
+		if d.IsNaN() {
+			return f, true
+		}
+		if inf := d.IsInf(); inf != 0 {
+			return f.SetInf(inf < 0), false
+		}
+		bi, exp, err := d.BigInt()
+		if err != nil {
+			return f, true
+		}
+		f.SetInt(bi)
+		pow := new(big.Float).SetPrec(256).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil))
+		if exp < 0 {
+			return f.Quo(f, pow), false
+		}
+		return f.Mul(f, pow), false
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.SetInt64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return f.SetUint64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(v.Float()) {
+			return f, true
+		}
+		return f.SetFloat64(v.Float()), false
+	}
+	return f, true
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// compareDocuments compares documents pair by pair, first by the type of
+// the value, then by the key and lastly by the value itself
+func compareDocuments(a, b []element) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareInts(int64(typeRank(a[i].val)), int64(typeRank(b[i].val))); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a[i].key, b[i].key); c != 0 {
+			return c
+		}
+		if c := compareValues(a[i].val, b[i].val); c != 0 {
+			return c
+		}
+	}
+	return compareInts(int64(len(a)), int64(len(b)))
+}
+
+// documentElements returns the key value pairs of a struct, map or bson.D.
+// Struct fields are keyed by their bson name and maps are ordered by key
+func documentElements(v reflect.Value) []element {
+	v = indirectValue(v)
+	if d, ok := v.Interface().(primitive.D); ok {
+		elements := make([]element, len(d))
+		for i, e := range d {
+			elements[i] = element{key: e.Key, val: reflect.ValueOf(e.Value)}
+		}
+		return elements
+	}
+
+	var elements []element
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" || field.Tag.Get("bson") == "-" {
+				continue
+			}
+			key := bsonTagName(field)
+			if key == "" {
+				key = strings.ToLower(field.Name)
+			}
+			elements = append(elements, element{key: key, val: v.Field(i)})
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if iter.Key().Kind() == reflect.String {
+				elements = append(elements, element{key: iter.Key().String(), val: iter.Value()})
+			}
+		}
+		sort.Slice(elements, func(i, j int) bool { return elements[i].key < elements[j].key })
+	}
+	return elements
+}
+
+func binaryValue(v reflect.Value) (byte, []byte) {
+	if b, ok := v.Interface().(primitive.Binary); ok {
+		return b.Subtype, b.Data
+	}
+	data := make([]byte, v.Len())
+	for i := range data {
+		data[i] = byte(v.Index(i).Uint())
+	}
+	return 0, data
+}
+
+func timeValue(v reflect.Value) time.Time {
+	switch t := v.Interface().(type) {
+	case time.Time:
+		return t
+	case primitive.DateTime:
+		return time.Unix(0, int64(t)*int64(time.Millisecond))
+	case *timestamppb.Timestamp:
+		return t.AsTime()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,111 @@
+package mock
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_compareValues(t *testing.T) {
+	decimal, err := primitive.ParseDecimal128("2.5")
+	if err != nil {
+		t.Fatal("error parsing decimal:", err)
+	}
+	var nilPtr *testObj
+	tests := []struct {
+		name string
+		a, b interface{}
+		want int
+	}{
+		{"minkey_null", primitive.MinKey{}, nil, -1},
+		{"null_nil_ptr", nil, nilPtr, 0},
+		{"null_number", primitive.Null{}, -100, -1},
+		{"null_nil_bytes", nil, []byte(nil), 0},
+		{"nil_bytes_binary", []byte(nil), []byte{}, -1},
+		{"number_string", 1000, "1", -1},
+		{"string_object", "z", bson.M{}, -1},
+		{"object_array", bson.D{{Key: "a", Value: 1}}, []int{1}, -1},
+		{"array_binary", []int{1}, []byte{1}, -1},
+		{"binary_objectid", []byte{1}, primitive.NewObjectID(), -1},
+		{"objectid_bool", primitive.NewObjectID(), false, -1},
+		{"bool_date", true, time.Unix(0, 0), -1},
+		{"date_timestamp", time.Unix(0, 0), primitive.Timestamp{}, -1},
+		{"timestamp_regex", primitive.Timestamp{T: 10}, primitive.Regex{}, -1},
+		{"regex_maxkey", primitive.Regex{Pattern: "a"}, primitive.MaxKey{}, -1},
+		{"int_int32", 5, int32(5), 0},
+		{"int64_float", int64(2), 2.5, -1},
+		{"uint_int", uint64(math.MaxUint64), int64(math.MaxInt64), 1},
+		{"float_decimal", 2.5, decimal, 0},
+		{"nan_number", math.NaN(), math.Inf(-1), -1},
+		{"nan_nan", math.NaN(), math.NaN(), 0},
+		{"string", "abc", "abd", -1},
+		{"bool", true, false, 1},
+		{"time_datetime", time.Unix(10, 0), primitive.NewDateTimeFromTime(time.Unix(10, 0)), 0},
+		{"time_timestamppb", time.Unix(20, 0), timestamppb.New(time.Unix(10, 0)), 1},
+		{"ptr_value", &[]int{1, 2}, []int{1, 3}, -1},
+		{"array_length", []int{1, 2}, primitive.A{1}, 1},
+		{"object_key", bson.M{"a": 1}, bson.D{{Key: "b", Value: 1}}, -1},
+		{"object_value_type", bson.M{"a": "1"}, bson.M{"a": 1}, 1},
+		{"struct_map", testObj{Name: "a", Value: 1}, bson.D{
+			{Key: "name", Value: "a"}, {Key: "value", Value: 1}, {Key: "time", Value: time.Time{}}}, 0},
+		{"binary_subtype", primitive.Binary{Subtype: 1, Data: []byte{1}}, []byte{2}, 1},
+		{"timestamp", primitive.Timestamp{T: 1, I: 2}, primitive.Timestamp{T: 1, I: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := reflect.ValueOf(tt.a), reflect.ValueOf(tt.b)
+			if got := compareValues(a, b); got != tt.want {
+				t.Errorf("compareValues() = %v, want %v", got, tt.want)
+			}
+			if got := compareValues(b, a); got != -tt.want {
+				t.Errorf("compareValues() reversed = %v, want %v", got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestDB_FindAll_SortMixedTypes(t *testing.T) {
+	t.Parallel()
+	d := CreateDB()
+	values := []interface{}{true, "b", 2.5, nil, int32(1), "a", time.Unix(0, 0), uint8(3)}
+	for i, v := range values {
+		if err := d.Insert("col", bson.M{"i": i, "v": v}); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+	// a document without the sort key is sorted as null
+	if err := d.Insert("col", bson.M{"i": len(values)}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+
+	tests := []struct {
+		name string
+		opts *db.Options
+		want []int
+	}{
+		{"ascending", db.CreateOptions().SetSort("v", 1), []int{3, 8, 4, 2, 7, 5, 1, 0, 6}},
+		{"descending", db.CreateOptions().SetSort("v", -1), []int{6, 0, 1, 5, 7, 2, 4, 3, 8}},
+		{"sort_skip_limit", db.CreateOptions().SetSort("v", 1).SetSkip(2).SetLimit(3), []int{4, 2, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []bson.M
+			if err := d.FindAll("col", &got, nil, tt.opts); err != nil {
+				t.Fatal("DB.FindAll() error:", err)
+			}
+			var order []int
+			for _, doc := range got {
+				order = append(order, doc["i"].(int))
+			}
+			if !reflect.DeepEqual(order, tt.want) {
+				t.Errorf("DB.FindAll() order = %v, want %v", order, tt.want)
+			}
+		})
+	}
+}
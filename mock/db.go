@@ -4,42 +4,111 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
-	"time"
 
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/sschwartz96/stockpile/db"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
 )
 
 type DB struct {
 	sync.RWMutex
 	collectionMap map[string](*[]interface{})
+	// registry is set when documents are stored as BSON, see CreateBSONDB
+	registry *bsoncodec.Registry
+	// textIndices holds the weights of the searched fields, see SetTextIndex
+	textIndices map[string]map[string]int
+	// changeStreams are the open streams returned by Watch
+	changeStreams map[*changeStream]bool
+	changeLog     []*changeEntry
+	changeSeq     int64
+	// shared marks the collections whose documents are shared with a snapshot
+	shared map[string]bool
+	// store persists the collections to disk, see CreateFileDB
+	store *fileStore
+	// calls and expectations are recorded for verification, see Expect
+	callsMu      sync.Mutex
+	calls        []Call
+	expectations []*Expectation
+	faults       []*Fault
+	// unhealthy is the error Ping fails with, see SetUnhealthy
+	unhealthy error
+	// closed is set by Close until Open is called
+	closed bool
 }
 
 func CreateDB() *DB {
 	return &DB{collectionMap: make(map[string]*[]interface{})}
 }
 
+// Open opens the DB after Close, a DB is open once created. The collections
+// are cleared unless they are stored on disk, in which case they are reloaded
 func (d *DB) Open(ctx context.Context) error {
+	if err := d.record(Call{Method: "Open"}); err != nil {
+		return err
+	}
+	d.Lock()
+	defer d.Unlock()
+	if d.store != nil {
+		if err := d.closeStore(); err != nil {
+			return fmt.Errorf("mock.DB.Open() error: %v", err)
+		}
+		if err := d.load(); err != nil {
+			return fmt.Errorf("mock.DB.Open() error: %v", err)
+		}
+		d.closed = false
+		return nil
+	}
 	d.collectionMap = make(map[string](*[]interface{}))
+	d.shared = nil
+	d.closed = false
 	return nil
 }
 
+// Close closes the DB and its change streams, every other method returns a
+// *db.ClosedError until the DB is opened again. Closing a closed DB does nothing
 func (d *DB) Close(ctx context.Context) error {
-	d.collectionMap = nil
+	if err := d.record(Call{Method: "Close"}); err != nil {
+		return err
+	}
+	d.Lock()
+	defer d.Unlock()
+	if d.closed {
+		return nil
+	}
+	if d.store != nil {
+		if err := d.closeStore(); err != nil {
+			return fmt.Errorf("mock.DB.Close() error: %v", err)
+		}
+	}
+	d.closeStreams()
+	// an empty map rather than nil so calls racing with Close do not panic
+	d.collectionMap = make(map[string](*[]interface{}))
+	d.shared = nil
+	d.closed = true
 	return nil
 }
 
-func (d *DB) Insert(collection string, object interface{}) error {
+func (d *DB) Insert(collection string, object interface{}, opts ...*db.WriteOptions) error {
+	writeOpts := db.MergeWriteOptions(opts...)
+	if err := d.record(Call{Method: "Insert", Collection: collection, WriteOptions: writeOpts, Object: object}); err != nil {
+		return err
+	}
+	if err := writeOpts.Validate(); err != nil {
+		return fmt.Errorf("mock.DB.Insert() error: %w", err)
+	}
 	d.Lock()
 	defer d.Unlock()
+	if err := d.checkClosed("Insert"); err != nil {
+		return err
+	}
+	return d.insert(collection, object)
+}
 
+// insert adds the object to the collection, the caller must hold the lock of the DB
+func (d *DB) insert(collection string, object interface{}) error {
 	if collection == "" {
 		return errors.New("collection is empty")
 	}
@@ -48,10 +117,17 @@ func (d *DB) Insert(collection string, object interface{}) error {
 	}
 
 	// this allows pointers to be derefenced
-	objVal := reflect.ValueOf(object)
-	toInsert := objVal.Interface()
-	if objVal.Kind() == reflect.Ptr {
-		toInsert = objVal.Elem().Interface()
+	toInsert, err := d.encode(object)
+	if err != nil {
+		return err
+	}
+
+	index := 0
+	if col := d.collectionMap[collection]; col != nil {
+		index = len(*col)
+	}
+	if err := d.persist(logInsert, collection, index, toInsert); err != nil {
+		return err
 	}
 
 	if d.collectionMap[collection] == nil {
@@ -59,18 +135,28 @@ func (d *DB) Insert(collection string, object interface{}) error {
 		col[0] = toInsert
 		d.collectionMap[collection] = &col
 	} else {
-		col := d.collectionMap[collection]
+		col := d.own(collection)
 		*col = append(*col, toInsert)
 	}
 
+	d.publish(collection, db.OperationInsert, toInsert)
 	return nil
 }
 
 func (d *DB) FindOne(collection string, object interface{}, filter *db.Filter, opts *db.Options) error {
+	if err := d.record(Call{Method: "FindOne", Collection: collection, Filter: filter, Options: opts, Object: object}); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("mock.DB.FindOne() error: %w", err)
+	}
 	d.RLock()
 	defer d.RUnlock()
+	if err := d.checkClosed("FindOne"); err != nil {
+		return err
+	}
 	if d.collectionMap[collection] == nil {
-		return errors.New("collection doesn not exist")
+		return fmt.Errorf("mock.DB.FindOne() error: %w", &db.UnknownCollectionError{Collection: collection})
 	}
 
 	// grab the value of the object which should be a ptr
@@ -95,8 +181,17 @@ func (d *DB) FindOne(collection string, object interface{}, filter *db.Filter, o
 }
 
 func (d *DB) FindAll(collection string, slice interface{}, filter *db.Filter, opts *db.Options) error {
+	if err := d.record(Call{Method: "FindAll", Collection: collection, Filter: filter, Options: opts, Object: slice}); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("mock.DB.FindAll() error: %w", err)
+	}
 	d.RLock()
 	defer d.RUnlock()
+	if err := d.checkClosed("FindAll"); err != nil {
+		return err
+	}
 	pointerVal := reflect.ValueOf(slice)
 	if pointerVal.Kind() != reflect.Ptr {
 		return errors.New("slice arg must be a *pointer* (to slice)")
@@ -108,7 +203,7 @@ func (d *DB) FindAll(collection string, slice interface{}, filter *db.Filter, op
 
 	err := d.findAll(collection, &sliceVal, filter, opts)
 	if err != nil {
-		return fmt.Errorf("error in finding: %v", err)
+		return fmt.Errorf("error in finding: %w", err)
 	}
 
 	pointerVal.Elem().Set(sliceVal)
@@ -117,7 +212,7 @@ func (d *DB) FindAll(collection string, slice interface{}, filter *db.Filter, op
 
 func (d *DB) findAll(collection string, sliceVal *reflect.Value, filter *db.Filter, opts *db.Options) error {
 	if d.collectionMap[collection] == nil {
-		return errors.New("collection does not not exist")
+		return &db.UnknownCollectionError{Collection: collection}
 	}
 
 	if filter == nil {
@@ -128,84 +223,58 @@ func (d *DB) findAll(collection string, sliceVal *reflect.Value, filter *db.Filt
 		opts = db.CreateOptions()
 	}
 
-	var limitCounter int64
-	dataSlice := *d.collectionMap[collection]
-
-	for i := opts.Skip; int(i) < len(dataSlice); i++ {
-		data := dataSlice[i]
-		if compareInterfaceToFilter(data, filter) {
-			appendSliceVal(sliceVal, data)
-
-			limitCounter++
-			if limitCounter == opts.Limit {
-				break
-			}
+	// gather every matching document
+	var matched []interface{}
+	for _, data := range *d.collectionMap[collection] {
+		if d.matches(data, filter) {
+			matched = append(matched, data)
 		}
 	}
 
-	// sort the data
+	// sort the data before skipping and limiting
 	if opts.Sort != nil && opts.Sort.Value != 0 {
-		sortSlice(sliceVal, opts.Sort)
+		matchedVal := reflect.ValueOf(matched)
+		d.sortSlice(&matchedVal, opts.Sort)
+	}
+
+	if opts.Skip >= int64(len(matched)) {
+		return nil
+	}
+	matched = matched[opts.Skip:]
+	if opts.Limit > 0 && opts.Limit < int64(len(matched)) {
+		matched = matched[:opts.Limit]
+	}
+
+	for _, data := range matched {
+		if err := d.appendDocument(sliceVal, data); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func sortSlice(sliceVal *reflect.Value, sortOpt *db.SortOption) *reflect.Value {
-	sort.SliceStable(sliceVal.Interface(), generateLessFunc(sliceVal, sortOpt))
+func (d *DB) sortSlice(sliceVal *reflect.Value, sortOpt *db.SortOption) *reflect.Value {
+	sort.SliceStable(sliceVal.Interface(), d.generateLessFunc(sliceVal, sortOpt))
 	return sliceVal
 }
 
-func generateLessFunc(sliceVal *reflect.Value, sortOpt *db.SortOption) func(i, j int) bool {
+// generateLessFunc orders the documents by the sort key using the MongoDB
+// BSON comparison order, documents missing the key are sorted as null
+func (d *DB) generateLessFunc(sliceVal *reflect.Value, sortOpt *db.SortOption) func(i, j int) bool {
 	return func(i, j int) bool {
-		var iVal, jVal reflect.Value
-		if sliceVal.Index(i).Kind() == reflect.Ptr {
-			iVal = sliceVal.Index(i).Elem().FieldByNameFunc(matchFieldFunc(sortOpt.Key))
-			jVal = sliceVal.Index(j).Elem().FieldByNameFunc(matchFieldFunc(sortOpt.Key))
-		} else {
-			iVal = sliceVal.Index(i).FieldByNameFunc(matchFieldFunc(sortOpt.Key))
-			jVal = sliceVal.Index(j).FieldByNameFunc(matchFieldFunc(sortOpt.Key))
-		}
-		switch iVal.Kind() {
-
-		case reflect.Int, reflect.Int64, reflect.Int32:
-			if sortOpt.Value > 0 {
-				return iVal.Int() < jVal.Int()
-			}
-			return iVal.Int() > jVal.Int()
-
-		case reflect.String:
-			if sortOpt.Value > 0 {
-				return iVal.String() < jVal.String()
-			}
-			return iVal.String() > jVal.String()
-
-		default:
-			if iVal.IsZero() {
-				return false
-			}
-			switch iVal.Type() {
-
-			case reflect.ValueOf(time.Now()).Type():
-				if sortOpt.Value > 0 {
-					return iVal.Interface().(time.Time).Before(jVal.Interface().(time.Time))
-				}
-				return iVal.Interface().(time.Time).After(jVal.Interface().(time.Time))
-
-			case reflect.ValueOf(timestamppb.Now()).Type():
-				iStamp := iVal.Interface().(*timestamppb.Timestamp).AsTime()
-				jStamp := jVal.Interface().(*timestamppb.Timestamp).AsTime()
-				if sortOpt.Value > 0 {
-					return iStamp.Before(jStamp)
-				}
-				return iStamp.After(jStamp)
-			}
-		}
+		return d.isLess(sliceVal.Index(i).Interface(), sliceVal.Index(j).Interface(), sortOpt)
+	}
+}
 
-		//log.Println("generateLessFunc() didn't match any cases")
-		//log.Println(iVal)
-		return false
+// isLess reports whether document a sorts before document b
+func (d *DB) isLess(a, b interface{}, sortOpt *db.SortOption) bool {
+	aVal, _ := d.lookupField(a, sortOpt.Key)
+	bVal, _ := d.lookupField(b, sortOpt.Key)
+	if sortOpt.Value > 0 {
+		return compareValues(aVal, bVal) < 0
 	}
+	return compareValues(aVal, bVal) > 0
 }
 
 func matchFieldFunc(name string) func(string) bool {
@@ -214,56 +283,123 @@ func matchFieldFunc(name string) func(string) bool {
 	}
 }
 
-func (d *DB) Update(collection string, object interface{}, filter *db.Filter) error {
+func (d *DB) Update(collection string, object interface{}, filter *db.Filter, opts ...*db.WriteOptions) error {
+	writeOpts := db.MergeWriteOptions(opts...)
+	if err := d.record(Call{Method: "Update", Collection: collection, Filter: filter, WriteOptions: writeOpts, Object: object}); err != nil {
+		return err
+	}
+	if err := writeOpts.Validate(); err != nil {
+		return fmt.Errorf("mock.DB.Update() error: %w", err)
+	}
 	d.Lock()
 	defer d.Unlock()
+	if err := d.checkClosed("Update"); err != nil {
+		return err
+	}
 	if err := checkParams(collection, filter); err != nil {
 		return fmt.Errorf("mock.DB.Update() error: %v", err)
 	}
 
+	toUpdate, err := d.encode(object)
+	if err != nil {
+		return fmt.Errorf("mock.DB.Update() error: %v", err)
+	}
+
+	// like MongoDB a collection which was never written to matches nothing
 	dataSlice := d.collectionMap[collection]
+	if dataSlice == nil {
+		return fmt.Errorf("mock.DB.Update() error: %w", db.ErrNotFound)
+	}
 	for i, data := range *dataSlice {
-		if compareInterfaceToFilter(data, filter) {
-			return setValue(&(*dataSlice)[i], object)
+		if d.matches(data, filter) {
+			// like MongoDB an update leaving the document unchanged is not a change
+			if sameDocument(data, toUpdate) {
+				return nil
+			}
+			if err := d.persist(logUpdate, collection, i, toUpdate); err != nil {
+				return fmt.Errorf("mock.DB.Update() error: %v", err)
+			}
+			dataSlice = d.own(collection)
+			if err := setValue(&(*dataSlice)[i], toUpdate); err != nil {
+				return err
+			}
+			// Update replaces the document like MongoClient.Update
+			d.publish(collection, db.OperationReplace, toUpdate)
+			return nil
 		}
 	}
 
-	return errors.New("mock.DB.Update() error: no documents found")
+	return fmt.Errorf("mock.DB.Update() error: %w", db.ErrNotFound)
 }
 
-func (d *DB) Upsert(collection string, object interface{}, filter *db.Filter) error {
+func (d *DB) Upsert(collection string, object interface{}, filter *db.Filter, opts ...*db.WriteOptions) error {
+	writeOpts := db.MergeWriteOptions(opts...)
+	if err := d.record(Call{Method: "Upsert", Collection: collection, Filter: filter, WriteOptions: writeOpts, Object: object}); err != nil {
+		return err
+	}
+	if err := writeOpts.Validate(); err != nil {
+		return fmt.Errorf("mock.DB.Upsert() error: %w", err)
+	}
 	d.Lock()
+	defer d.Unlock()
+	if err := d.checkClosed("Upsert"); err != nil {
+		return err
+	}
 	if err := checkParams(collection, filter); err != nil {
-		d.Unlock()
-		return fmt.Errorf("mock.DB.Update() error: %v", err)
+		return fmt.Errorf("mock.DB.Upsert() error: %v", err)
 	}
 	dataSlice := d.collectionMap[collection]
 	// if collection is empty just insert
 	if dataSlice == nil {
-		d.Unlock()
-		return d.Insert(collection, object)
+		return d.insert(collection, object)
 	}
 	for i, data := range *dataSlice {
-		if compareInterfaceToFilter(data, filter) {
-			err := setValue(&(*dataSlice)[i], object)
-			d.Unlock()
+		if d.matches(data, filter) {
+			toUpdate, err := d.encode(object)
+			if err != nil || sameDocument(data, toUpdate) {
+				return err
+			}
+			err = d.persist(logUpdate, collection, i, toUpdate)
+			if err == nil {
+				dataSlice = d.own(collection)
+				err = setValue(&(*dataSlice)[i], toUpdate)
+			}
+			if err == nil {
+				d.publish(collection, db.OperationReplace, toUpdate)
+			}
 			return err
 		}
 	}
-	d.Unlock()
-	return d.Insert(collection, object)
+	return d.insert(collection, object)
 }
 
-func (d *DB) Delete(collection string, filter *db.Filter) error {
+func (d *DB) Delete(collection string, filter *db.Filter, opts ...*db.WriteOptions) error {
+	writeOpts := db.MergeWriteOptions(opts...)
+	if err := d.record(Call{Method: "Delete", Collection: collection, Filter: filter, WriteOptions: writeOpts}); err != nil {
+		return err
+	}
+	if err := writeOpts.Validate(); err != nil {
+		return fmt.Errorf("mock.DB.Delete() error: %w", err)
+	}
 	d.Lock()
 	defer d.Unlock()
+	if err := d.checkClosed("Delete"); err != nil {
+		return err
+	}
 	if err := checkParams(collection, filter); err != nil {
-		return fmt.Errorf("mock.DB.Update() error: %v", err)
+		return fmt.Errorf("mock.DB.Delete() error: %v", err)
 	}
 	dataSlice := d.collectionMap[collection]
-	//dataSlicePtr := &dataSlice
+	if dataSlice == nil {
+		// like MongoDB a collection which was never written to matches nothing
+		return fmt.Errorf("mock.DB.Delete() error: %w", db.ErrNotFound)
+	}
 	for i, data := range *dataSlice {
-		if compareInterfaceToFilter(data, filter) {
+		if d.matches(data, filter) {
+			if err := d.persist(logDelete, collection, i, nil); err != nil {
+				return fmt.Errorf("mock.DB.Delete() error: %v", err)
+			}
+			d.own(collection)
 			// get the slice value and slice value element
 			sliceVal := reflect.ValueOf(d.collectionMap[collection])
 			sliceValElem := sliceVal.Elem()
@@ -275,43 +411,95 @@ func (d *DB) Delete(collection string, filter *db.Filter) error {
 			// create a new slice and append
 			sliceValElem.Set(reflect.AppendSlice(part1, part2))
 
+			d.publish(collection, db.OperationDelete, data)
 			return nil
 		}
 	}
-	return errors.New("mock.DB.Delete(): no documents found")
+	return fmt.Errorf("mock.DB.Delete() error: %w", db.ErrNotFound)
 }
 
+// Search performs a text search upon the fields of the collection the way a
+// MongoDB $text query does. The search string is tokenized and stemmed,
+// stop words are ignored, "quoted phrases" must be contained and words or
+// phrases prefixed with a minus exclude documents. Every matching document is
+// returned once, ordered by relevance
 func (d *DB) Search(collection string, search string, fields []string, slice interface{}) error {
+	if err := d.record(Call{Method: "Search", Collection: collection, Search: search, Fields: fields, Object: slice}); err != nil {
+		return err
+	}
+	return d.searchWithOptions("Search", collection, search, fields, slice, nil, nil)
+}
+
+// SearchWithOptions performs the same text search as Search, limited to the
+// documents matching filter. Results are sorted by relevance unless opts sets
+// a sort, and the relevance is written to opts.ScoreField when it is set
+func (d *DB) SearchWithOptions(collection, search string, fields []string, slice interface{}, filter *db.Filter, opts *db.Options) error {
+	if err := d.record(Call{Method: "SearchWithOptions", Collection: collection, Search: search, Fields: fields, Filter: filter, Options: opts, Object: slice}); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("mock.DB.SearchWithOptions() error: %w", err)
+	}
+	return d.searchWithOptions("SearchWithOptions", collection, search, fields, slice, filter, opts)
+}
+
+func (d *DB) searchWithOptions(method, collection, search string, fields []string, slice interface{}, filter *db.Filter, opts *db.Options) error {
 	d.RLock()
 	defer d.RUnlock()
-	dataSlice := d.collectionMap[collection]
+	if err := d.checkClosed(method); err != nil {
+		return err
+	}
 	pointerVal := reflect.ValueOf(slice)
+	if pointerVal.Kind() != reflect.Ptr || pointerVal.Elem().Kind() != reflect.Slice {
+		return errors.New("slice arg must be a *pointer* to a *slice*")
+	}
 	sliceVal := pointerVal.Elem()
-	for _, data := range *dataSlice {
-		dataVal := reflect.ValueOf(data)
-		for _, field := range fields {
-			fieldValue := dataVal.FieldByNameFunc(matchFieldFunc(field))
-			if containsLower(fieldValue.String(), search) {
-				appendSliceVal(&sliceVal, data)
+
+	if opts == nil {
+		opts = db.CreateOptions()
+	}
+
+	results, err := d.textSearch(collection, search, fields)
+	if err != nil {
+		return fmt.Errorf("mock.DB.Search() error: %w", err)
+	}
+
+	if filter != nil {
+		filtered := results[:0]
+		for _, result := range results {
+			if d.matches(result.data, filter) {
+				filtered = append(filtered, result)
 			}
 		}
+		results = filtered
 	}
 
-	pointerVal.Elem().Set(sliceVal)
-	return nil
-}
+	if opts.Sort != nil && opts.Sort.Value != 0 {
+		sort.SliceStable(results, func(i, j int) bool {
+			return d.isLess(results[i].data, results[j].data, opts.Sort)
+		})
+	}
 
-func appendSliceVal(sliceVal *reflect.Value, data interface{}) {
-	dataVal := reflect.ValueOf(data)
-	// if the slice contains pointers to object
-	if reflect.TypeOf(sliceVal.Interface()).Elem().Kind() == reflect.Ptr {
-		p := reflect.New(reflect.TypeOf(data))
-		p.Elem().Set(reflect.ValueOf(data))
+	if opts.Skip >= int64(len(results)) {
+		results = nil
+	} else {
+		results = results[opts.Skip:]
+	}
+	if opts.Limit > 0 && opts.Limit < int64(len(results)) {
+		results = results[:opts.Limit]
+	}
 
-		dataVal = p
+	for _, result := range results {
+		if err := d.appendDocument(&sliceVal, result.data); err != nil {
+			return err
+		}
+		if opts.ScoreField != "" {
+			setScore(sliceVal.Index(sliceVal.Len()-1), opts.ScoreField, result.score)
+		}
 	}
 
-	*sliceVal = reflect.Append(*sliceVal, dataVal)
+	pointerVal.Elem().Set(sliceVal)
+	return nil
 }
 
 func checkParams(collection string, filter *db.Filter) error {
@@ -324,77 +512,20 @@ func checkParams(collection string, filter *db.Filter) error {
 	return nil
 }
 
-func compareInterfaceToFilter(a interface{}, filter *db.Filter) bool {
-	aVal := reflect.ValueOf(a)
-
-	if !aVal.IsValid() {
+func (d *DB) compareInterfaceToFilter(a interface{}, filter *db.Filter) bool {
+	if !reflect.ValueOf(a).IsValid() {
 		return false
 	}
 
-	if aVal.Kind() == reflect.Ptr {
-		aVal = aVal.Elem()
-	}
-
 	for filterKey, filterV := range *filter {
-		filterVal, ok := deferencedValueOf(filterV)
-		if !ok {
-			log.Println("not okay filterVal")
-			return false
-		}
-		fieldVal, ok := deferencedValueOf(aVal.FieldByNameFunc(matchFieldFunc(filterKey)).Interface())
-		if !ok {
-			log.Println("not okay fieldVal")
+		field, found := d.lookupField(a, filterKey)
+		if !matchFilterValue(field, found, filterV) {
 			return false
 		}
-		if !isEqual(filterVal, fieldVal) {
-			//log.Printf("compareInterfaceToFilter() not equal: \n\t%v\n\t%v\n", filterVal.Interface(), fieldVal.Interface())
-			return false
-		}
-		//log.Printf("compareInterfaceToFilter() EQUAL: \n\t%v\n\t%v\n", filterVal.Interface(), fieldVal.Interface())
 	}
 	return true
 }
 
-func deferencedValueOf(i interface{}) (reflect.Value, bool) {
-	iVal := reflect.ValueOf(i)
-	if !iVal.IsValid() || !iVal.CanInterface() {
-		return iVal, false
-	}
-	if iVal.Kind() == reflect.Ptr {
-		iVal = iVal.Elem()
-		if !iVal.IsValid() || !iVal.CanInterface() {
-			return iVal, false
-		}
-	}
-	return iVal, true
-}
-
-func isEqual(a, b reflect.Value) bool {
-	//if (a.Kind() == b.Kind() && a.Type() == b.Type()) || (isIntKind(a) && isIntKind(b)) {
-	switch a.Kind() {
-	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
-		log.Println("comparing int:")
-		return a.Int() == b.Int()
-	case reflect.String, reflect.Bool:
-		log.Println("comparing string, bool:")
-		return a.Interface() == b.Interface()
-	default:
-		log.Println("comparing type:", a.Type())
-		return cmp.Equal(a.Interface(), b.Interface(), cmpopts.IgnoreUnexported(a.Interface()))
-	}
-	//}
-	//log.Println("kind or type not match")
-	//log.Printf("a.Type() = %v, b.Type() = %v", a.Type(), b.Type())
-	//log.Printf("a.Kind() = %v, b.Kind() = %v", a.Kind(), b.Kind())
-	//return false
-}
-
-func isIntKind(v reflect.Value) bool {
-	return v.Kind() == reflect.Int || v.Kind() == reflect.Int8 ||
-		v.Kind() == reflect.Int16 || v.Kind() == reflect.Int32 ||
-		v.Kind() == reflect.Int64
-}
-
 func containsLower(s, sub string) bool {
 	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
 }
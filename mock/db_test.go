@@ -2,8 +2,10 @@ package mock
 
 import (
 	"context"
+	"errors"
 	"log"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -664,8 +666,9 @@ func Test_Search(t *testing.T) {
 			},
 			d:       testDB,
 			wantErr: false,
+			// obj2 has the fewest words besides stop words so it is most relevant
 			endingSlice: &[]testObj{
-				obj1, obj2, obj3,
+				obj2, obj1, obj3,
 			},
 		},
 		{
@@ -776,9 +779,166 @@ func Test_sortSlice(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := sortSlice(tt.args.sliceVal, tt.args.sortOpt); !reflect.DeepEqual(got.Interface(), tt.want.Interface()) {
+			if got := CreateDB().sortSlice(tt.args.sliceVal, tt.args.sortOpt); !reflect.DeepEqual(got.Interface(), tt.want.Interface()) {
 				t.Errorf("sortSlice() =\ngot: %v\nwant: %v\n", got.Interface(), tt.want.Interface())
 			}
 		})
 	}
 }
+
+func TestDB_UnknownCollection(t *testing.T) {
+	d := CreateDB()
+	filter := &db.Filter{"name": "foo"}
+	var obj testObj
+	var objs []testObj
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"FindOne", func() error { return d.FindOne("missing", &obj, filter, nil) }},
+		{"FindAll", func() error { return d.FindAll("missing", &objs, filter, nil) }},
+		{"Search", func() error { return d.Search("missing", "foo", []string{"name"}, &objs) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unknown *db.UnknownCollectionError
+			if err := tt.call(); !errors.As(err, &unknown) || unknown.Collection != "missing" {
+				t.Errorf("DB.%v() error = %v, want unknown collection", tt.name, err)
+			}
+		})
+	}
+	// like MongoDB writes to a collection which was never written to match nothing
+	if err := d.Update("missing", obj, filter); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("DB.Update() error = %v, want db.ErrNotFound", err)
+	}
+	if err := d.Delete("missing", filter); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("DB.Delete() error = %v, want db.ErrNotFound", err)
+	}
+}
+
+func TestDB_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	d := CreateDB()
+	if err := d.Insert("users", testObj{Name: "foo"}); err != nil {
+		t.Fatal("DB.Insert() error:", err)
+	}
+	stream, err := d.Watch(ctx, "users", nil, nil)
+	if err != nil {
+		t.Fatal("DB.Watch() error:", err)
+	}
+
+	if err := d.Close(ctx); err != nil {
+		t.Fatal("DB.Close() error:", err)
+	}
+	if err := d.Close(ctx); err != nil {
+		t.Errorf("DB.Close() of a closed DB error = %v", err)
+	}
+	// open change streams end with the closed error
+	if stream.Next(ctx) || !errors.Is(stream.Err(), db.ErrClosed) {
+		t.Errorf("changeStream.Err() = %v, want db.ErrClosed", stream.Err())
+	}
+
+	var found []testObj
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{"Insert", func() error { return d.Insert("users", testObj{Name: "bar"}) }},
+		{"FindAll", func() error { return d.FindAll("users", &found, nil, nil) }},
+		{"Update", func() error { return d.Update("users", testObj{Name: "bar"}, &db.Filter{"name": "foo"}) }},
+		{"Delete", func() error { return d.Delete("users", &db.Filter{"name": "foo"}) }},
+		{"Search", func() error { return d.Search("users", "foo", []string{"name"}, &found) }},
+		{"Ping", func() error { return d.Ping(ctx) }},
+	}
+	for _, tt := range calls {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var closed *db.ClosedError
+			if !errors.As(err, &closed) {
+				t.Errorf("DB.%v() error = %v, want *db.ClosedError", tt.name, err)
+			}
+		})
+	}
+
+	if err := d.Open(ctx); err != nil {
+		t.Fatal("DB.Open() error:", err)
+	}
+	if err := d.Insert("users", testObj{Name: "bar"}); err != nil {
+		t.Errorf("DB.Insert() after Open error = %v", err)
+	}
+
+	// calls racing with Close fail without panicking
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				err := d.Insert("users", testObj{Name: "baz"})
+				if err != nil && !errors.Is(err, db.ErrClosed) {
+					t.Errorf("DB.Insert() error = %v", err)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		_ = d.Close(ctx)
+		_ = d.Open(ctx)
+	}
+	wg.Wait()
+}
+
+func TestDB_CloseWithWriters(t *testing.T) {
+	ctx := context.Background()
+	d := CreateDB()
+	if err := d.Insert("users", testObj{Name: "foo"}); err != nil {
+		t.Fatal("DB.Insert() error:", err)
+	}
+	stream, err := d.Watch(ctx, "users", nil, nil)
+	if err != nil {
+		t.Fatal("DB.Watch() error:", err)
+	}
+
+	var wg sync.WaitGroup
+	started := make(chan struct{}, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started <- struct{}{}
+			for {
+				err := d.Insert("users", testObj{Name: "bar"})
+				if err == nil {
+					err = d.Update("users", testObj{Name: "bar", Value: 1}, &db.Filter{"name": "bar"})
+				}
+				if errors.Is(err, db.ErrClosed) {
+					return
+				}
+				if err != nil {
+					t.Errorf("write error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		<-started
+	}
+	if err := d.Close(ctx); err != nil {
+		t.Fatal("DB.Close() error:", err)
+	}
+	wg.Wait()
+
+	// no write made it into the closed DB or its streams
+	d.RLock()
+	collections := len(d.collectionMap)
+	d.RUnlock()
+	if collections != 0 {
+		t.Errorf("closed DB holds %d collection(s), want none", collections)
+	}
+	for stream.Next(ctx) {
+	}
+	if !errors.Is(stream.Err(), db.ErrClosed) {
+		t.Errorf("stream error = %v, want db.ErrClosed", stream.Err())
+	}
+}
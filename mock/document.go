@@ -0,0 +1,152 @@
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// lookupField finds the value of the field name within doc. The document can
+// be a struct, a map with string keys, a bson.D or a bson.Raw, which is decoded
+// with the registry of the DB. Names are matched ignoring case and underscores,
+// struct fields also match their bson tag
+func (d *DB) lookupField(doc interface{}, name string) (reflect.Value, bool) {
+	docVal := reflect.ValueOf(doc)
+	if docVal.Kind() == reflect.Ptr {
+		docVal = docVal.Elem()
+	}
+	if !docVal.IsValid() || !docVal.CanInterface() {
+		return reflect.Value{}, false
+	}
+
+	match := matchFieldFunc(name)
+	switch doc := docVal.Interface().(type) {
+	case bson.Raw:
+		rawVal, ok := lookupRaw(doc, name)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		var v interface{}
+		if err := rawVal.UnmarshalWithRegistry(d.bsonRegistry(), &v); err != nil {
+			return reflect.Value{}, false
+		}
+		return concreteValue(reflect.ValueOf(v))
+	case primitive.D:
+		for _, e := range doc {
+			if match(e.Key) {
+				return concreteValue(reflect.ValueOf(e.Value))
+			}
+		}
+		return reflect.Value{}, false
+	}
+
+	switch docVal.Kind() {
+	case reflect.Struct:
+		t := docVal.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if tag := bsonTagName(t.Field(i)); tag != "" && match(tag) {
+				return concreteValue(docVal.Field(i))
+			}
+		}
+		return concreteValue(docVal.FieldByNameFunc(match))
+	case reflect.Map:
+		if docVal.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		iter := docVal.MapRange()
+		for iter.Next() {
+			if match(iter.Key().String()) {
+				return concreteValue(iter.Value())
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// concreteValue unwraps interface values, so values held in maps and bson.D
+// have the kind of the value they hold
+func concreteValue(v reflect.Value) (reflect.Value, bool) {
+	for v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return v, v.IsValid() && v.CanInterface()
+}
+
+// bsonTagName returns the key given to the struct field by its bson tag
+func bsonTagName(field reflect.StructField) string {
+	tag := field.Tag.Get("bson")
+	if idx := strings.Index(tag, ","); idx != -1 {
+		tag = tag[:idx]
+	}
+	if tag == "-" {
+		return ""
+	}
+	return tag
+}
+
+// bsonRegistry returns the registry used to convert documents between types
+func (d *DB) bsonRegistry() *bsoncodec.Registry {
+	if d.registry == nil {
+		return bson.DefaultRegistry
+	}
+	return d.registry
+}
+
+// convertDocument returns the stored data as a value of type to. Data which
+// isn't assignable, such as a map read into a struct, is converted by
+// marshalling into bson and unmarshalling into the new type
+func (d *DB) convertDocument(data interface{}, to reflect.Type) (reflect.Value, error) {
+	raw, isRaw := data.(bson.Raw)
+	if !isRaw {
+		dataVal := reflect.ValueOf(data)
+		if dataVal.IsValid() && dataVal.Type().AssignableTo(to) {
+			return dataVal, nil
+		}
+		b, err := bson.MarshalWithRegistry(d.bsonRegistry(), data)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("error converting %T to %v: %v", data, to, err)
+		}
+		raw = bson.Raw(b)
+	}
+	converted := reflect.New(to)
+	if err := bson.UnmarshalWithRegistry(d.bsonRegistry(), raw, converted.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("error unmarshalling document into %v: %v", to, err)
+	}
+	return converted.Elem(), nil
+}
+
+// appendDocument appends the stored data to sliceVal, converting it to
+// the element type of the slice
+func (d *DB) appendDocument(sliceVal *reflect.Value, data interface{}) error {
+	elemType := sliceVal.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	docVal, err := d.convertDocument(data, elemType)
+	if err != nil {
+		return err
+	}
+	// if the slice contains pointers to object
+	if isPtr {
+		p := reflect.New(elemType)
+		p.Elem().Set(docVal)
+		docVal = p
+	}
+	*sliceVal = reflect.Append(*sliceVal, docVal)
+	return nil
+}
+
+// sameDocument reports whether two stored documents hold the same values
+func sameDocument(a, b interface{}) bool {
+	if rawA, ok := a.(bson.Raw); ok {
+		rawB, ok := b.(bson.Raw)
+		return ok && bytes.Equal(rawA, rawB)
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,157 @@
+package mock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+type taggedObj struct {
+	ID   string `bson:"_id"`
+	Desc string `bson:"description"`
+}
+
+func Test_lookupField(t *testing.T) {
+	raw, err := bson.Marshal(bson.M{"name": "raw", "value": int32(4)})
+	if err != nil {
+		t.Fatal("error marshalling:", err)
+	}
+	tests := []struct {
+		name   string
+		doc    interface{}
+		field  string
+		want   interface{}
+		wantOk bool
+	}{
+		{"struct", testObj{Name: "foo"}, "name", "foo", true},
+		{"struct_ptr", &testObj{Value: 3}, "VALUE", 3, true},
+		{"struct_tag", taggedObj{Desc: "bar"}, "description", "bar", true},
+		{"struct_missing", testObj{}, "missing", nil, false},
+		{"map", map[string]interface{}{"name": "foo"}, "Name", "foo", true},
+		{"bson.M", bson.M{"user_name": "foo"}, "username", "foo", true},
+		{"bson.D", bson.D{{Key: "a", Value: 1}, {Key: "value", Value: 2}}, "value", 2, true},
+		{"bson.D_missing", bson.D{{Key: "a", Value: 1}}, "b", nil, false},
+		{"bson.Raw", bson.Raw(raw), "value", int32(4), true},
+		{"int_map", map[int]string{1: "a"}, "1", nil, false},
+		{"nil", nil, "name", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CreateDB().lookupField(tt.doc, tt.field)
+			if ok != tt.wantOk {
+				t.Fatalf("lookupField() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("lookupField() = %v, want %v", got.Interface(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDB_lookupField_Registry(t *testing.T) {
+	raw, err := bson.Marshal(bson.M{"value": int32(4)})
+	if err != nil {
+		t.Fatal("error marshalling:", err)
+	}
+	// nested values are decoded with the registry of the DB
+	registry := bson.NewRegistryBuilder().RegisterTypeMapEntry(bsontype.Int32, reflect.TypeOf(int64(0))).Build()
+	got, ok := CreateBSONDB(registry).lookupField(bson.Raw(raw), "value")
+	if !ok || got.Interface() != int64(4) {
+		t.Errorf("lookupField() = %v, want int64 4", got)
+	}
+}
+
+func TestDB_MixedDocuments(t *testing.T) {
+	t.Parallel()
+	for name, d := range map[string]*DB{"go": CreateDB(), "bson": CreateBSONDB(nil)} {
+		d := d
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			docs := []interface{}{
+				testObj{Name: "struct", Value: 3},
+				bson.M{"name": "map", "value": 1},
+				&bson.D{{Key: "name", Value: "bson.D"}, {Key: "value", Value: 2}},
+			}
+			for _, doc := range docs {
+				if err := d.Insert("col", doc); err != nil {
+					t.Fatal("error inserting:", err)
+				}
+			}
+
+			var structs []testObj
+			err := d.FindAll("col", &structs, nil, db.CreateOptions().SetSort("value", 1))
+			if err != nil {
+				t.Fatal("DB.FindAll() error:", err)
+			}
+			want := []testObj{{Name: "map", Value: 1}, {Name: "bson.D", Value: 2}, {Name: "struct", Value: 3}}
+			if !sliceDeepEqual(&structs, &want) {
+				t.Errorf("DB.FindAll() = %v, want %v", structs, want)
+			}
+
+			var m bson.M
+			if err := d.FindOne("col", &m, &db.Filter{"name": "struct"}, nil); err != nil {
+				t.Fatal("DB.FindOne() error:", err)
+			}
+			if m["name"] != "struct" || m["time"] == nil {
+				t.Errorf("DB.FindOne() struct into map = %v", m)
+			}
+
+			var obj testObj
+			if err := d.FindOne("col", &obj, &db.Filter{"value": 2}, nil); err != nil {
+				t.Fatal("DB.FindOne() error:", err)
+			}
+			if obj.Name != "bson.D" {
+				t.Errorf("DB.FindOne() bson.D into struct = %v", obj)
+			}
+
+			if err := d.Update("col", bson.M{"name": "updated", "value": 10}, &db.Filter{"name": "map"}); err != nil {
+				t.Fatal("DB.Update() error:", err)
+			}
+			if err := d.Delete("col", &db.Filter{"name": "bson.D"}); err != nil {
+				t.Fatal("DB.Delete() error:", err)
+			}
+
+			var found []*testObj
+			if err := d.Search("col", "Update", []string{"name"}, &found); err != nil {
+				t.Fatal("DB.Search() error:", err)
+			}
+			wantFound := []testObj{{Name: "updated", Value: 10}}
+			if !sliceDeepEqual(&found, &wantFound) {
+				t.Errorf("DB.Search() = %v, want %v", found, wantFound)
+			}
+		})
+	}
+}
+
+func TestDB_convertDocument(t *testing.T) {
+	d := CreateDB()
+	now := time.Unix(100, 0).UTC()
+	tests := []struct {
+		name    string
+		data    interface{}
+		to      reflect.Type
+		want    interface{}
+		wantErr bool
+	}{
+		{"same_type", testObj{Name: "a"}, reflect.TypeOf(testObj{}), testObj{Name: "a"}, false},
+		{"interface", testObj{Name: "a"}, reflect.TypeOf((*interface{})(nil)).Elem(), testObj{Name: "a"}, false},
+		{"map_to_struct", bson.M{"name": "a", "time": now}, reflect.TypeOf(testObj{}), testObj{Name: "a", Time: now}, false},
+		{"struct_to_map", taggedObj{ID: "1", Desc: "b"}, reflect.TypeOf(bson.M{}), bson.M{"_id": "1", "description": "b"}, false},
+		{"not_document", 123, reflect.TypeOf(testObj{}), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.convertDocument(tt.data, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DB.convertDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Errorf("DB.convertDocument() = %v, want %v", got.Interface(), tt.want)
+			}
+		})
+	}
+}
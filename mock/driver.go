@@ -0,0 +1,26 @@
+package mock
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+func init() {
+	db.Register("mem", connect)
+}
+
+// connect creates a DB for db.Connect from a "mem://" URL. The query can set
+// bson=true to store documents as BSON, or dir=path to persist them on disk
+func connect(ctx context.Context, u *url.URL) (db.Database, error) {
+	query := u.Query()
+	if dir := query.Get("dir"); dir != "" {
+		return CreateFileDB(dir, nil)
+	}
+	if bson, _ := strconv.ParseBool(query.Get("bson")); bson {
+		return CreateBSONDB(nil), nil
+	}
+	return CreateDB(), nil
+}
@@ -0,0 +1,41 @@
+package mock
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+func TestConnect(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		url      string
+		wantBSON bool
+		wantFile bool
+	}{
+		{"mem", "mem://", false, false},
+		{"bson", "mem://?bson=true", true, false},
+		{"file", "mem://?dir=" + url.QueryEscape(dir), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, err := db.Connect(context.Background(), tt.url)
+			if err != nil {
+				t.Fatal("db.Connect() error:", err)
+			}
+			d := database.(*DB)
+			if (d.registry != nil) != tt.wantBSON || (d.store != nil) != tt.wantFile {
+				t.Errorf("db.Connect() bson = %v, file = %v", d.registry != nil, d.store != nil)
+			}
+			if err := d.Insert("col", testObj{}); err != nil {
+				t.Error("DB.Insert() error:", err)
+			}
+			if err := d.Close(context.Background()); err != nil {
+				t.Error("DB.Close() error:", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package mock
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// Fault makes matching calls to the DB fail or slow down, it is created
+// with DB.Fail and matches every call until narrowed down
+type Fault struct {
+	// mu is the callsMu of the DB, held while the fault is applied
+	mu *sync.Mutex
+
+	method     string
+	collection string
+	filter     *db.Filter
+	hasFilter  bool
+	when       func(Call) bool
+	// onCall is the only matching call to fail, counting from 1, when above zero
+	onCall int
+	// times is the number of calls left to fail, unlimited when negative
+	times int
+	err   error
+	delay time.Duration
+	seen  int
+}
+
+// Fail adds a fault to the DB, it is applied before the call is executed
+// so a failed call leaves the stored documents untouched
+func (d *DB) Fail() *Fault {
+	f := &Fault{mu: &d.callsMu, times: -1}
+	d.callsMu.Lock()
+	defer d.callsMu.Unlock()
+	d.faults = append(d.faults, f)
+	return f
+}
+
+// ResetFaults removes every fault of the DB
+func (d *DB) ResetFaults() {
+	d.callsMu.Lock()
+	defer d.callsMu.Unlock()
+	d.faults = nil
+}
+
+// set changes the fault under the lock of the DB, as calls may be
+// applying it concurrently
+func (f *Fault) set(change func()) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change()
+	return f
+}
+
+func (f *Fault) call(method, collection string) *Fault {
+	return f.set(func() {
+		f.method = method
+		f.collection = collection
+	})
+}
+
+func (f *Fault) Insert(collection string) *Fault {
+	return f.call("Insert", collection)
+}
+
+func (f *Fault) FindOne(collection string) *Fault {
+	return f.call("FindOne", collection)
+}
+
+func (f *Fault) FindAll(collection string) *Fault {
+	return f.call("FindAll", collection)
+}
+
+func (f *Fault) Update(collection string) *Fault {
+	return f.call("Update", collection)
+}
+
+func (f *Fault) Upsert(collection string) *Fault {
+	return f.call("Upsert", collection)
+}
+
+func (f *Fault) Delete(collection string) *Fault {
+	return f.call("Delete", collection)
+}
+
+func (f *Fault) Search(collection string) *Fault {
+	return f.call("Search", collection)
+}
+
+func (f *Fault) SearchWithOptions(collection string) *Fault {
+	return f.call("SearchWithOptions", collection)
+}
+
+func (f *Fault) Watch(collection string) *Fault {
+	return f.call("Watch", collection)
+}
+
+func (f *Fault) Ping() *Fault {
+	return f.call("Ping", "")
+}
+
+func (f *Fault) Drop(collection string) *Fault {
+	return f.call("Drop", collection)
+}
+
+func (f *Fault) Reset(collection string) *Fault {
+	return f.call("Reset", collection)
+}
+
+// Collection matches calls of any method upon the collection
+func (f *Fault) Collection(collection string) *Fault {
+	return f.call("", collection)
+}
+
+// WithFilter only matches calls made with an equal filter
+func (f *Fault) WithFilter(filter *db.Filter) *Fault {
+	return f.set(func() {
+		f.filter = filter
+		f.hasFilter = true
+	})
+}
+
+// When only matches calls for which the predicate returns true, it runs
+// without the locks of the DB so it may call the DB itself
+func (f *Fault) When(predicate func(Call) bool) *Fault {
+	return f.set(func() { f.when = predicate })
+}
+
+// OnCall only fails the nth matching call, counting from 1
+func (f *Fault) OnCall(n int) *Fault {
+	return f.set(func() { f.onCall = n })
+}
+
+// Times fails the next n matching calls, after which calls succeed again
+func (f *Fault) Times(n int) *Fault {
+	return f.set(func() { f.times = n })
+}
+
+// Return sets the error returned by failed calls, such as db.ErrTimeout,
+// without an error the fault only adds its delay
+func (f *Fault) Return(err error) *Fault {
+	return f.set(func() { f.err = err })
+}
+
+// Delay sleeps before executing matching calls, or before returning the error
+func (f *Fault) Delay(delay time.Duration) *Fault {
+	return f.set(func() { f.delay = delay })
+}
+
+// matches reports whether the call is of the method, collection and filter
+// of the fault, the predicate of When is left to the caller
+func (f *Fault) matches(c Call) bool {
+	if f.method != "" && c.Method != f.method {
+		return false
+	}
+	if f.collection != "" && c.Collection != f.collection {
+		return false
+	}
+	if f.hasFilter && !reflect.DeepEqual(c.Filter, f.filter) {
+		return false
+	}
+	return true
+}
+
+// count counts a matching call and reports whether the fault applies to
+// it, so it must be called once per call
+func (f *Fault) count() bool {
+	f.seen++
+	if f.onCall > 0 && f.seen != f.onCall {
+		return false
+	}
+	if f.times == 0 {
+		return false
+	}
+	if f.times > 0 {
+		f.times--
+	}
+	return true
+}
+
+// fault applies the faults of the DB matching the call, it returns the
+// error of the first fault with one after sleeping for the total delay.
+// The predicates of When run without the lock, so they may call the DB
+func (d *DB) fault(c Call) error {
+	type candidate struct {
+		fault *Fault
+		when  func(Call) bool
+	}
+	d.callsMu.Lock()
+	var candidates []candidate
+	for _, f := range d.faults {
+		if f.matches(c) {
+			candidates = append(candidates, candidate{fault: f, when: f.when})
+		}
+	}
+	d.callsMu.Unlock()
+
+	matched := candidates[:0]
+	for _, cand := range candidates {
+		if cand.when == nil || cand.when(c) {
+			matched = append(matched, cand)
+		}
+	}
+
+	d.callsMu.Lock()
+	var delay time.Duration
+	var err error
+	for _, cand := range matched {
+		if cand.fault.count() {
+			delay += cand.fault.delay
+			if err == nil {
+				err = cand.fault.err
+			}
+		}
+	}
+	d.callsMu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+func TestDB_Fail(t *testing.T) {
+	filter := &db.Filter{"name": "foo"}
+	tests := []struct {
+		name  string
+		fault func(f *Fault)
+		// want is the error expected from each of the three updates
+		want []error
+	}{
+		{"every_call", func(f *Fault) { f.Return(db.ErrNetwork) }, []error{db.ErrNetwork, db.ErrNetwork, db.ErrNetwork}},
+		{"method", func(f *Fault) { f.Update("users").Return(db.ErrTimeout) }, []error{db.ErrTimeout, db.ErrTimeout, db.ErrTimeout}},
+		{"other_method", func(f *Fault) { f.Delete("users").Return(db.ErrTimeout) }, []error{nil, nil, nil}},
+		{"other_collection", func(f *Fault) { f.Collection("posts").Return(db.ErrTimeout) }, []error{nil, nil, nil}},
+		{"on_call", func(f *Fault) { f.Update("users").OnCall(2).Return(db.ErrDuplicateKey) }, []error{nil, db.ErrDuplicateKey, nil}},
+		{"times", func(f *Fault) { f.Collection("users").Times(2).Return(db.ErrNetwork) }, []error{db.ErrNetwork, db.ErrNetwork, nil}},
+		{"filter", func(f *Fault) { f.WithFilter(&db.Filter{"name": "foo"}).Return(db.ErrTimeout) }, []error{db.ErrTimeout, db.ErrTimeout, db.ErrTimeout}},
+		{"predicate", func(f *Fault) {
+			f.When(func(c Call) bool { return c.Object.(testObj).Value == 2 }).Return(db.ErrTimeout)
+		}, []error{nil, db.ErrTimeout, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := CreateDB()
+			if err := d.Insert("users", testObj{Name: "foo"}); err != nil {
+				t.Fatal("error inserting:", err)
+			}
+			tt.fault(d.Fail())
+			for i, want := range tt.want {
+				obj := testObj{Name: "foo", Value: i + 1}
+				if err := d.Update("users", obj, filter); !errors.Is(err, want) {
+					t.Errorf("DB.Update() call %d error = %v, want %v", i+1, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDB_Fail_WhenCallsDB(t *testing.T) {
+	d := CreateDB()
+	if err := d.Insert("users", testObj{Name: "foo"}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	d.Fail().Update("users").When(func(c Call) bool {
+		var found testObj
+		if err := d.FindOne("users", &found, &db.Filter{"name": "foo"}, nil); err != nil {
+			t.Error("DB.FindOne() error:", err)
+		}
+		return len(d.Calls()) > 4
+	}).Return(db.ErrTimeout)
+
+	done := make(chan error, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			done <- d.Update("users", testObj{Name: "foo", Value: i}, &db.Filter{"name": "foo"})
+		}
+	}()
+	for i, want := range []error{nil, db.ErrTimeout} {
+		select {
+		case err := <-done:
+			if !errors.Is(err, want) {
+				t.Errorf("DB.Update() call %d error = %v, want %v", i+1, err, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("DB.Update() deadlocked in the predicate of the fault")
+		}
+	}
+}
+
+func TestDB_Fail_NoChanges(t *testing.T) {
+	d := CreateDB()
+	d.Fail().Insert("users").Return(db.ErrDuplicateKey)
+	if err := d.Insert("users", testObj{Name: "foo"}); err != db.ErrDuplicateKey {
+		t.Fatalf("DB.Insert() error = %v, want %v", err, db.ErrDuplicateKey)
+	}
+	if _, err := d.Watch(context.Background(), "users", nil, nil); err != nil {
+		t.Fatal("DB.Watch() error:", err)
+	}
+	d.ResetFaults()
+	var found []testObj
+	if err := d.FindAll("users", &found, nil, nil); err == nil {
+		t.Errorf("DB.FindAll() found %v after a failed insert", found)
+	}
+	if len(d.Calls()) != 3 {
+		t.Errorf("DB.Calls() = %v, failed calls should be recorded", d.Calls())
+	}
+}
+
+func TestDB_Fail_Delay(t *testing.T) {
+	d := CreateDB()
+	d.Fail().FindOne("users").Delay(20 * time.Millisecond)
+	d.Fail().Insert("users").Delay(20 * time.Millisecond).Return(db.ErrTimeout)
+
+	start := time.Now()
+	if err := d.Insert("users", testObj{}); err != db.ErrTimeout {
+		t.Errorf("DB.Insert() error = %v, want %v", err, db.ErrTimeout)
+	}
+	var obj testObj
+	if err := d.FindOne("users", &obj, &db.Filter{}, nil); err == nil {
+		t.Errorf("DB.FindOne() found %v after a failed insert", obj)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("calls took %v, want at least 40ms", elapsed)
+	}
+}
+
+func TestDB_Fail_Concurrent(t *testing.T) {
+	// faults are built while calls are applying them, run with -race
+	d := CreateDB()
+	if err := d.Insert("users", testObj{Name: "foo"}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	f := d.Fail().Update("users")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = d.Update("users", testObj{Name: "foo"}, &db.Filter{"name": "foo"})
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		f.Times(1).Delay(0).Return(db.ErrTimeout).OnCall(0).When(nil).WithFilter(&db.Filter{"name": "foo"})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,121 @@
+package mock
+
+import (
+	"log"
+	"reflect"
+	"strings"
+)
+
+// matchFilterValue reports whether the field of a document satisfies the
+// filter value. The filter value is either the value the field must equal or
+// a document of query operators such as {"$gte": 1, "$lt": 10}
+func matchFilterValue(field reflect.Value, found bool, filterV interface{}) bool {
+	filterVal := reflect.ValueOf(filterV)
+	if operators, ok := operatorElements(filterVal); ok {
+		for _, op := range operators {
+			if !matchOperator(field, found, op.key, op.val) {
+				return false
+			}
+		}
+		return true
+	}
+	return matchEqual(field, found, filterVal)
+}
+
+// operatorElements returns the elements of v when v is a document whose keys
+// are all query operators
+func operatorElements(v reflect.Value) ([]element, bool) {
+	if typeRank(v) != rankObject {
+		return nil, false
+	}
+	elements := documentElements(v)
+	if len(elements) == 0 {
+		return nil, false
+	}
+	for _, e := range elements {
+		if !strings.HasPrefix(e.key, "$") {
+			return nil, false
+		}
+	}
+	return elements, true
+}
+
+func matchOperator(field reflect.Value, found bool, op string, operand reflect.Value) bool {
+	switch op {
+	case "$eq":
+		return matchEqual(field, found, operand)
+	case "$ne":
+		return !matchEqual(field, found, operand)
+	case "$gt":
+		return found && matchRange(field, operand, func(c int) bool { return c > 0 })
+	case "$gte":
+		return found && matchRange(field, operand, func(c int) bool { return c >= 0 })
+	case "$lt":
+		return found && matchRange(field, operand, func(c int) bool { return c < 0 })
+	case "$lte":
+		return found && matchRange(field, operand, func(c int) bool { return c <= 0 })
+	case "$in":
+		return matchIn(field, found, operand)
+	case "$nin":
+		return !matchIn(field, found, operand)
+	case "$exists":
+		operand = indirectValue(operand)
+		return found == (operand.IsValid() && !operand.IsZero())
+	}
+	log.Println("mock: unsupported query operator:", op)
+	return false
+}
+
+// matchEqual compares field to value, a null value matches missing fields and
+// array fields match when any of their elements is equal
+func matchEqual(field reflect.Value, found bool, value reflect.Value) bool {
+	if typeRank(value) == rankNull {
+		return !found || typeRank(field) == rankNull
+	}
+	if !found {
+		return false
+	}
+	return anyValue(field, func(v reflect.Value) bool {
+		return compareValues(v, value) == 0
+	})
+}
+
+// matchRange compares field to operand only when both are of the same BSON
+// type, the same type bracketing MongoDB applies to range operators
+func matchRange(field, operand reflect.Value, cmp func(int) bool) bool {
+	return anyValue(field, func(v reflect.Value) bool {
+		return typeRank(v) == typeRank(operand) && cmp(compareValues(v, operand))
+	})
+}
+
+func matchIn(field reflect.Value, found bool, operand reflect.Value) bool {
+	operand = indirectValue(operand)
+	if typeRank(operand) != rankArray {
+		log.Println("mock: $in and $nin require an array")
+		return false
+	}
+	for i := 0; i < operand.Len(); i++ {
+		if matchEqual(field, found, operand.Index(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyValue calls fn with the value and, when the value is an array,
+// with each of its elements until fn returns true
+func anyValue(v reflect.Value, fn func(reflect.Value) bool) bool {
+	if fn(v) {
+		return true
+	}
+	if typeRank(v) != rankArray {
+		return false
+	}
+	v = indirectValue(v)
+	for i := 0; i < v.Len(); i++ {
+		if fn(v.Index(i)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_compareInterfaceToFilter(t *testing.T) {
+	now := time.Now()
+	doc := bson.M{
+		"name":  "foo",
+		"value": int64(10),
+		"time":  now,
+		"tags":  []string{"a", "b"},
+		"ptr":   &testObj{Name: "bar"},
+		"null":  nil,
+	}
+	tests := []struct {
+		name   string
+		filter db.Filter
+		want   bool
+	}{
+		{"equal", db.Filter{"name": "foo"}, true},
+		{"equal_cross_number", db.Filter{"value": 10.0}, true},
+		{"not_equal", db.Filter{"name": "bar"}, false},
+		{"pointer_filter", db.Filter{"value": func() *int { i := 10; return &i }()}, true},
+		{"null_missing", db.Filter{"missing": nil}, true},
+		{"null_value", db.Filter{"null": nil}, true},
+		{"null_not_null", db.Filter{"name": nil}, false},
+		{"array_element", db.Filter{"tags": "b"}, true},
+		{"array_whole", db.Filter{"tags": []string{"a", "b"}}, true},
+		{"array_no_element", db.Filter{"tags": "c"}, false},
+		{"gt", db.Filter{"value": bson.M{"$gt": 9}}, true},
+		{"gt_equal", db.Filter{"value": bson.M{"$gt": 10}}, false},
+		{"gte_lte", db.Filter{"value": bson.M{"$gte": 10, "$lte": int32(10)}}, true},
+		{"lt", db.Filter{"value": bson.D{{Key: "$lt", Value: 10.5}}}, true},
+		{"range_type_bracketing", db.Filter{"name": bson.M{"$gt": 1}}, false},
+		{"range_time", db.Filter{"time": bson.M{"$gt": now.Add(-time.Second), "$lt": now.Add(time.Second)}}, true},
+		{"range_array_element", db.Filter{"tags": bson.M{"$gt": "a"}}, true},
+		{"range_missing", db.Filter{"missing": bson.M{"$lt": 100}}, false},
+		{"ne", db.Filter{"name": bson.M{"$ne": "bar"}}, true},
+		{"ne_missing", db.Filter{"missing": bson.M{"$ne": "bar"}}, true},
+		{"in", db.Filter{"value": bson.M{"$in": []interface{}{"10", 10}}}, true},
+		{"in_array_field", db.Filter{"tags": bson.M{"$in": []string{"c", "a"}}}, true},
+		{"nin", db.Filter{"value": bson.M{"$nin": []int{1, 2}}}, true},
+		{"exists", db.Filter{"name": bson.M{"$exists": true}, "missing": bson.M{"$exists": false}}, true},
+		{"not_exists", db.Filter{"name": bson.M{"$exists": false}}, false},
+		{"unsupported_operator", db.Filter{"name": bson.M{"$regex": "f"}}, false},
+		{"struct_equal", db.Filter{"ptr": testObj{Name: "bar"}}, true},
+		{"multiple", db.Filter{"name": "foo", "value": bson.M{"$lt": 5}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CreateDB().compareInterfaceToFilter(doc, &tt.filter); got != tt.want {
+				t.Errorf("compareInterfaceToFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDB_FindAll_RangeFilter(t *testing.T) {
+	t.Parallel()
+	for name, d := range map[string]*DB{"go": CreateDB(), "bson": CreateBSONDB(nil)} {
+		d := d
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			for i := 1; i <= 5; i++ {
+				if err := d.Insert("col", testObj{Name: "obj", Value: i, Time: time.Unix(int64(i), 0).UTC()}); err != nil {
+					t.Fatal("error inserting:", err)
+				}
+			}
+			var got []testObj
+			filter := &db.Filter{
+				"value": bson.M{"$gt": int64(1)},
+				"time":  bson.M{"$lte": time.Unix(4, 0)},
+			}
+			if err := d.FindAll("col", &got, filter, db.CreateOptions().SetSort("value", -1)); err != nil {
+				t.Fatal("DB.FindAll() error:", err)
+			}
+			if len(got) != 3 || got[0].Value != 4 || got[2].Value != 2 {
+				t.Errorf("DB.FindAll() = %v, want values 4, 3, 2", got)
+			}
+		})
+	}
+}
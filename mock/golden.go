@@ -0,0 +1,271 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// UpdateGoldenEnv is the environment variable making AssertGolden write the
+// golden files instead of comparing them, such as STOCKPILE_UPDATE_GOLDEN=1
+const UpdateGoldenEnv = "STOCKPILE_UPDATE_GOLDEN"
+
+// updateGolden reports whether UpdateGoldenEnv is set to a true value
+func updateGolden() bool {
+	update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnv))
+	return update
+}
+
+// Dump exports every collection as indented canonical Extended JSON, so the
+// types of numbers are kept: {"$numberInt": "1"} differs from {"$numberLong": "1"}.
+// Collections are sorted by name and the fields of documents by key so the
+// output is deterministic; documents keep the order they were inserted in.
+func (d *DB) Dump() ([]byte, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	collections := make([]string, 0, len(d.collectionMap))
+	for collection := range d.collectionMap {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	dump := bson.D{}
+	for _, collection := range collections {
+		docs := bson.A{}
+		for _, data := range *d.collectionMap[collection] {
+			raw, err := bson.MarshalWithRegistry(d.bsonRegistry(), data)
+			if err != nil {
+				return nil, fmt.Errorf("mock.DB.Dump() error marshalling document of %v: %v", collection, err)
+			}
+			var doc bson.D
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return nil, fmt.Errorf("mock.DB.Dump() error: %v", err)
+			}
+			docs = append(docs, sortKeys(doc))
+		}
+		dump = append(dump, bson.E{Key: collection, Value: docs})
+	}
+
+	data, err := bson.MarshalExtJSON(dump, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("mock.DB.Dump() error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return nil, fmt.Errorf("mock.DB.Dump() error: %v", err)
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// sortKeys sorts the keys of the document and of the documents within it
+func sortKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.D:
+		sorted := make(bson.D, len(v))
+		for i, e := range v {
+			sorted[i] = bson.E{Key: e.Key, Value: sortKeys(e.Value)}
+		}
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+		return sorted
+	case bson.A:
+		sorted := make(bson.A, len(v))
+		for i, item := range v {
+			sorted[i] = sortKeys(item)
+		}
+		return sorted
+	}
+	return v
+}
+
+// AssertGolden compares the dump of the DB with the golden file and reports
+// every difference to t. When UpdateGoldenEnv is set the file is written with
+// the dump instead
+func (d *DB) AssertGolden(t TestingT, path string) {
+	t.Helper()
+	got, err := d.Dump()
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = ioutil.WriteFile(path, got, 0644)
+		}
+		if err != nil {
+			t.Errorf("mock.DB.AssertGolden() error writing golden file: %v", err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("mock.DB.AssertGolden() error reading golden file, set %v=1 to create it: %v", UpdateGoldenEnv, err)
+		return
+	}
+	diffs, err := DiffDump(got, want)
+	if err != nil {
+		t.Errorf("mock.DB.AssertGolden() error: %v", err)
+		return
+	}
+	if len(diffs) > 0 {
+		t.Errorf("mock.DB state differs from %v, set %v=1 to update it:\n\t%v",
+			path, UpdateGoldenEnv, strings.Join(diffs, "\n\t"))
+	}
+}
+
+// DiffDump compares two dumps made by DB.Dump and returns a line for every
+// document or field which differs. Documents are matched by their _id, or by
+// their position within the collection when they have none. A collection
+// holding the same _id twice is an error, as its documents can not be matched
+func DiffDump(got, want []byte) ([]string, error) {
+	gotCols, err := parseDump(got)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing dump: %v", err)
+	}
+	wantCols, err := parseDump(want)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing golden dump: %v", err)
+	}
+
+	var diffs []string
+	for _, collection := range unionKeys(gotCols, wantCols) {
+		gotDocs, gotOk := gotCols[collection]
+		wantDocs, wantOk := wantCols[collection]
+		switch {
+		case !wantOk:
+			diffs = append(diffs, fmt.Sprintf("%v: unexpected collection with %d document(s)", collection, len(gotDocs)))
+			continue
+		case !gotOk:
+			diffs = append(diffs, fmt.Sprintf("%v: missing collection with %d document(s)", collection, len(wantDocs)))
+			continue
+		}
+		for _, key := range unionKeys(gotDocs, wantDocs) {
+			gotDoc, gotOk := gotDocs[key]
+			wantDoc, wantOk := wantDocs[key]
+			switch {
+			case !wantOk:
+				diffs = append(diffs, fmt.Sprintf("%v%v: unexpected document %v", collection, key, gotDoc.raw))
+				continue
+			case !gotOk:
+				diffs = append(diffs, fmt.Sprintf("%v%v: missing document %v", collection, key, wantDoc.raw))
+				continue
+			}
+			for _, field := range unionKeys(gotDoc.fields, wantDoc.fields) {
+				gotVal, gotOk := gotDoc.fields[field]
+				wantVal, wantOk := wantDoc.fields[field]
+				switch {
+				case !wantOk:
+					diffs = append(diffs, fmt.Sprintf("%v%v.%v: unexpected field = %v", collection, key, field, gotVal))
+				case !gotOk:
+					diffs = append(diffs, fmt.Sprintf("%v%v.%v: missing field, want %v", collection, key, field, wantVal))
+				case gotVal != wantVal:
+					diffs = append(diffs, fmt.Sprintf("%v%v.%v = %v, want %v", collection, key, field, gotVal, wantVal))
+				}
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// dumpDocument is a document of a dump with its fields flattened into paths
+type dumpDocument struct {
+	raw    string
+	fields map[string]string
+}
+
+// parseDump returns the documents of every collection keyed by their _id
+func parseDump(data []byte) (map[string]map[string]dumpDocument, error) {
+	var dump map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, err
+	}
+	collections := make(map[string]map[string]dumpDocument, len(dump))
+	for collection, docs := range dump {
+		byKey := make(map[string]dumpDocument, len(docs))
+		for i, raw := range docs {
+			var doc map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				return nil, fmt.Errorf("document %d of %v: %v", i, collection, err)
+			}
+			key := fmt.Sprintf("[%d]", i)
+			if id, ok := doc["_id"]; ok {
+				key = fmt.Sprintf("[_id=%v]", compactJSON(id))
+				if _, dup := byKey[key]; dup {
+					return nil, fmt.Errorf("duplicate document %v%v", collection, key)
+				}
+			}
+			fields := make(map[string]string)
+			flattenJSON("", raw, fields)
+			byKey[key] = dumpDocument{raw: compactJSON(raw), fields: fields}
+		}
+		collections[collection] = byKey
+	}
+	return collections, nil
+}
+
+// flattenJSON adds every value within the JSON to fields keyed by its path,
+// Extended JSON values such as {"$date": ...} are kept whole
+func flattenJSON(path string, raw json.RawMessage, fields map[string]string) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err == nil && len(object) > 0 && !isExtJSONValue(object) {
+		for key, value := range object {
+			p := key
+			if path != "" {
+				p = path + "." + key
+			}
+			flattenJSON(p, value, fields)
+		}
+		return
+	}
+	var array []json.RawMessage
+	if err := json.Unmarshal(raw, &array); err == nil && len(array) > 0 {
+		for i, value := range array {
+			flattenJSON(fmt.Sprintf("%v[%d]", path, i), value, fields)
+		}
+		return
+	}
+	fields[path] = compactJSON(raw)
+}
+
+func isExtJSONValue(object map[string]json.RawMessage) bool {
+	for key := range object {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+func compactJSON(raw json.RawMessage) string {
+	var out bytes.Buffer
+	if err := json.Compact(&out, raw); err != nil {
+		return string(raw)
+	}
+	return out.String()
+}
+
+// unionKeys returns the string keys of both maps sorted
+func unionKeys(a, b interface{}) []string {
+	set := make(map[string]bool)
+	for _, m := range []interface{}{a, b} {
+		for _, key := range reflect.ValueOf(m).MapKeys() {
+			set[key.String()] = true
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,136 @@
+package mock
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func goldenDB(t *testing.T) *DB {
+	d := CreateDB()
+	docs := []struct {
+		collection string
+		doc        interface{}
+	}{
+		{"users", bson.M{"_id": "2", "name": "bob", "tags": []string{"b", "a"}}},
+		{"users", taggedObj{ID: "1", Desc: "alice"}},
+		{"events", testObj{Name: "created", Value: 1, Time: time.Date(2020, 10, 20, 12, 0, 0, 0, time.UTC)}},
+	}
+	for _, doc := range docs {
+		if err := d.Insert(doc.collection, doc.doc); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+	return d
+}
+
+func TestDB_Dump(t *testing.T) {
+	t.Parallel()
+	first, err := goldenDB(t).Dump()
+	if err != nil {
+		t.Fatal("DB.Dump() error:", err)
+	}
+	for i := 0; i < 10; i++ {
+		got, err := goldenDB(t).Dump()
+		if err != nil {
+			t.Fatal("DB.Dump() error:", err)
+		}
+		if string(got) != string(first) {
+			t.Fatalf("DB.Dump() is not deterministic:\n%s\n%s", got, first)
+		}
+	}
+	if i, j := strings.Index(string(first), `"events"`), strings.Index(string(first), `"users"`); i > j {
+		t.Errorf("DB.Dump() collections not sorted:\n%s", first)
+	}
+	if i, j := strings.Index(string(first), `"_id": "2"`), strings.Index(string(first), `"name": "bob"`); i > j {
+		t.Errorf("DB.Dump() fields not sorted:\n%s", first)
+	}
+}
+
+func TestDB_AssertGolden(t *testing.T) {
+	d := goldenDB(t)
+	ft := &fakeT{}
+	d.AssertGolden(ft, "testdata/golden.json")
+	if len(ft.errors) != 0 {
+		t.Errorf("DB.AssertGolden() errors = %v", ft.errors)
+	}
+
+	if err := d.Insert("events", testObj{Name: "deleted"}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	ft = &fakeT{}
+	d.AssertGolden(ft, "testdata/golden.json")
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "events[1]: unexpected document") {
+		t.Errorf("DB.AssertGolden() errors = %v", ft.errors)
+	}
+
+	ft = &fakeT{}
+	d.AssertGolden(ft, "testdata/missing.json")
+	if len(ft.errors) != 1 {
+		t.Errorf("DB.AssertGolden() errors = %v, want missing file error", ft.errors)
+	}
+
+	path := filepath.Join(t.TempDir(), "golden", "state.json")
+	os.Setenv(UpdateGoldenEnv, "1")
+	d.AssertGolden(ft, path)
+	os.Unsetenv(UpdateGoldenEnv)
+	ft = &fakeT{}
+	d.AssertGolden(ft, path)
+	if len(ft.errors) != 0 {
+		t.Errorf("DB.AssertGolden() after update errors = %v", ft.errors)
+	}
+}
+
+func TestDiffDump(t *testing.T) {
+	want := `{"users": [{"_id": "1", "name": "alice", "address": {"city": "Paris"}, "tags": ["a", "b"]}, {"_id": "2"}],
+		"events": [{"name": "created", "time": {"$date": "2020-10-20T12:00:00Z"}}]}`
+	tests := []struct {
+		name    string
+		got     string
+		want    []string
+		wantErr bool
+	}{
+		{"equal", want, nil, false},
+		{"reordered", `{"events": [{"time": {"$date": "2020-10-20T12:00:00Z"}, "name": "created"}],
+			"users": [{"_id": "2"}, {"tags": ["a", "b"], "address": {"city": "Paris"}, "name": "alice", "_id": "1"}]}`, nil, false},
+		{"fields", `{"users": [{"_id": "1", "name": "alicia", "address": {"city": "Rome"}, "tags": ["a"], "age": 3}, {"_id": "2"}],
+			"events": [{"name": "created", "time": {"$date": "2020-10-21T12:00:00Z"}}]}`, []string{
+			`events[0].time = {"$date":"2020-10-21T12:00:00Z"}, want {"$date":"2020-10-20T12:00:00Z"}`,
+			`users[_id="1"].address.city = "Rome", want "Paris"`,
+			`users[_id="1"].age: unexpected field = 3`,
+			`users[_id="1"].name = "alicia", want "alice"`,
+			`users[_id="1"].tags[1]: missing field, want "b"`,
+		}, false},
+		{"documents", `{"users": [{"_id": "1", "name": "alice", "address": {"city": "Paris"}, "tags": ["a", "b"]}, {"_id": "3"}], "events": []}`, []string{
+			`events[0]: missing document {"name":"created","time":{"$date":"2020-10-20T12:00:00Z"}}`,
+			`users[_id="2"]: missing document {"_id":"2"}`,
+			`users[_id="3"]: unexpected document {"_id":"3"}`,
+		}, false},
+		{"collections", `{"users": [{"_id": "1", "name": "alice", "address": {"city": "Paris"}, "tags": ["a", "b"]}, {"_id": "2"}], "logs": []}`, []string{
+			`events: missing collection with 1 document(s)`,
+			`logs: unexpected collection with 0 document(s)`,
+		}, false},
+		{"invalid", `[]`, nil, true},
+		{"duplicate _id", `{"users": [{"_id": "1"}, {"_id": "1"}], "events": []}`, nil, true},
+		{"number types", `{"users": [{"_id": "1", "name": "alice", "address": {"city": "Paris"}, "tags": ["a", "b"]}, {"_id": "2"}],
+			"events": [{"name": "created", "time": {"$date": "2020-10-20T12:00:00Z"}, "value": {"$numberLong": "1"}}]}`, []string{
+			`events[0].value: unexpected field = {"$numberLong":"1"}`,
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffDump([]byte(tt.got), []byte(want))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiffDump() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffDump() = %v, want %v", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
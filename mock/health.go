@@ -0,0 +1,50 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// SetUnhealthy makes Ping and Health fail with err, simulating a database which
+// can not be reached, until it is called with nil. Latency is simulated with
+// a fault: d.Fail().Ping().Delay(d)
+func (d *DB) SetUnhealthy(err error) {
+	d.callsMu.Lock()
+	defer d.callsMu.Unlock()
+	d.unhealthy = err
+}
+
+// Ping fails when the DB is closed, made unhealthy with SetUnhealthy
+// or by a fault injected into Ping
+func (d *DB) Ping(ctx context.Context) error {
+	if err := d.record(Call{Method: "Ping"}); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("mock.DB.Ping() error: %w", err)
+	}
+	d.callsMu.Lock()
+	unhealthy := d.unhealthy
+	d.callsMu.Unlock()
+	if unhealthy != nil {
+		return fmt.Errorf("mock.DB.Ping() error: %w", unhealthy)
+	}
+	return nil
+}
+
+// Health pings the DB, the topology names its storage mode
+func (d *DB) Health(ctx context.Context) db.Health {
+	h := db.CheckHealth(ctx, d.Ping)
+	h.Topology = "mock"
+	d.RLock()
+	defer d.RUnlock()
+	switch {
+	case d.store != nil:
+		h.Topology = "mock file " + d.store.dir
+	case d.registry != nil:
+		h.Topology = "mock bson"
+	}
+	return h
+}
@@ -0,0 +1,90 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+func TestDB_Health(t *testing.T) {
+	ctx := context.Background()
+	d := CreateDB()
+	if h := d.Health(ctx); !h.Healthy || h.Err != nil || h.Topology != "mock" {
+		t.Errorf("DB.Health() = %+v, want healthy", h)
+	}
+	if h := CreateBSONDB(nil).Health(ctx); h.Topology != "mock bson" {
+		t.Errorf("DB.Health() topology = %v, want mock bson", h.Topology)
+	}
+
+	// forced unhealthy until cleared
+	d.SetUnhealthy(db.ErrNetwork)
+	if err := d.Ping(ctx); !errors.Is(err, db.ErrNetwork) {
+		t.Errorf("DB.Ping() error = %v, want db.ErrNetwork", err)
+	}
+	if h := d.Health(ctx); h.Healthy || !errors.Is(h.Err, db.ErrNetwork) {
+		t.Errorf("DB.Health() = %+v, want unhealthy", h)
+	}
+	d.SetUnhealthy(nil)
+	if err := d.Ping(ctx); err != nil {
+		t.Errorf("DB.Ping() error = %v after recovering", err)
+	}
+
+	// latency and failures injected with faults
+	d.Fail().Ping().Times(1).Delay(5 * time.Millisecond)
+	if h := d.Health(ctx); !h.Healthy || h.Latency < 5*time.Millisecond {
+		t.Errorf("DB.Health() = %+v, want a latency of 5ms", h)
+	}
+	d.Fail().Ping().OnCall(1).Return(db.ErrTimeout)
+	if err := d.Ping(ctx); !errors.Is(err, db.ErrTimeout) {
+		t.Errorf("DB.Ping() error = %v, want db.ErrTimeout", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := d.Ping(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("DB.Ping() error = %v, want context.Canceled", err)
+	}
+
+	if err := d.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Ping(ctx); err == nil {
+		t.Errorf("DB.Ping() expected error when closed")
+	}
+
+	d.Expect().Ping().Times(8)
+	d.Verify(t)
+}
+
+func TestDB_Health_Concurrent(t *testing.T) {
+	// health checks run while the DB is closed and opened again, run with -race
+	ctx := context.Background()
+	d, err := CreateFileDB(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal("CreateFileDB() error:", err)
+	}
+	defer d.Close(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if h := d.Health(ctx); h.Topology == "" {
+				t.Errorf("DB.Health() = %+v, want a topology", h)
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := d.Close(ctx); err != nil {
+			t.Fatal("DB.Close() error:", err)
+		}
+		if err := d.Open(ctx); err != nil {
+			t.Fatal("DB.Open() error:", err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,357 @@
+package mock
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// stopWords are english words ignored by the text search,
+// they are the same words ignored by a MongoDB text index
+var stopWords = toSet(strings.Fields(`a about above after again against all am an and any are
+	aren't as at be because been before being below between both but by can't cannot
+	could couldn't did didn't do does doesn't doing don't down during each few for from
+	further had hadn't has hasn't have haven't having he he'd he'll he's her here here's
+	hers herself him himself his how how's i i'd i'll i'm i've if in into is isn't it
+	it's its itself let's me more most mustn't my myself no nor not of off on once only or
+	other ought our ours ourselves out over own same shan't she she'd she'll she's should
+	shouldn't so some such than that that's the their theirs them themselves then there
+	there's these they they'd they'll they're they've this those through to too under
+	until up very was wasn't we we'd we'll we're we've were weren't what what's when
+	when's where where's which while who who's whom why why's with won't would wouldn't
+	you you'd you'll you're you've your yours yourself yourselves`))
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// tokenize splits text into lower case words on anything other than letters,
+// digits and apostrophes
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// terms tokenizes text and returns the stems of every word which isn't a stop word
+func terms(text string) []string {
+	var stems []string
+	for _, token := range tokenize(text) {
+		if stopWords[token] {
+			continue
+		}
+		token = strings.Trim(token, "'")
+		if token == "" {
+			continue
+		}
+		stems = append(stems, stem(token))
+	}
+	return stems
+}
+
+// SetTextIndex sets the weights of the fields searched within the collection,
+// like the weights of a MongoDB text index. Fields without a weight have a
+// weight of 1, and a search without fields searches every weighted field
+func (d *DB) SetTextIndex(collection string, weights map[string]int) {
+	d.Lock()
+	defer d.Unlock()
+	if d.textIndices == nil {
+		d.textIndices = make(map[string]map[string]int)
+	}
+	d.textIndices[collection] = weights
+}
+
+// textSearch searches the fields of every document within the collection
+func (d *DB) textSearch(collection, search string, fields []string) ([]textResult, error) {
+	dataSlice := d.collectionMap[collection]
+	if dataSlice == nil {
+		return nil, &db.UnknownCollectionError{Collection: collection}
+	}
+	weights := d.textIndices[collection]
+	if len(fields) == 0 {
+		for field := range weights {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("no fields to search")
+	}
+	idx := d.newTextIndex(*dataSlice, fields, weights)
+	return idx.search(*dataSlice, parseTextQuery(search)), nil
+}
+
+// textQuery is a parsed search string using the MongoDB $text syntax:
+// words are matched individually, "quoted phrases" must be contained and
+// words or phrases prefixed with a minus are excluded
+type textQuery struct {
+	terms          []string
+	negatedTerms   []string
+	phrases        []string
+	negatedPhrases []string
+}
+
+func parseTextQuery(search string) textQuery {
+	var q textQuery
+	for len(search) > 0 {
+		search = strings.TrimLeftFunc(search, unicode.IsSpace)
+		if search == "" {
+			break
+		}
+		negated := strings.HasPrefix(search, "-")
+		if negated {
+			search = search[1:]
+		}
+
+		if strings.HasPrefix(search, "\"") {
+			phrase := search[1:]
+			end := strings.Index(phrase, "\"")
+			if end == -1 {
+				end = len(phrase)
+				search = ""
+			} else {
+				search = phrase[end+1:]
+			}
+			phrase = strings.ToLower(phrase[:end])
+			if negated {
+				q.negatedPhrases = append(q.negatedPhrases, phrase)
+				continue
+			}
+			q.phrases = append(q.phrases, phrase)
+			// the words of a phrase contribute to the score
+			q.terms = append(q.terms, terms(phrase)...)
+			continue
+		}
+
+		end := strings.IndexFunc(search, unicode.IsSpace)
+		if end == -1 {
+			end = len(search)
+		}
+		word := search[:end]
+		search = search[end:]
+		if negated {
+			q.negatedTerms = append(q.negatedTerms, terms(word)...)
+		} else {
+			q.terms = append(q.terms, terms(word)...)
+		}
+	}
+	return q
+}
+
+// textField holds the indexed content of a single field of a document
+type textField struct {
+	weight float64
+	text   string // lower case text, used to match phrases
+	scores map[string]float64
+}
+
+// textIndex is an in-memory text index over the fields of a collection
+type textIndex struct {
+	weights map[string]int
+	docs    [][]textField
+}
+
+// textResult is a document matched by a text search
+type textResult struct {
+	data  interface{}
+	score float64
+}
+
+// newTextIndex indexes the string fields, and arrays of strings, of every
+// document. Fields have a weight of 1 unless given in weights
+func (d *DB) newTextIndex(docs []interface{}, fields []string, weights map[string]int) *textIndex {
+	idx := &textIndex{weights: weights, docs: make([][]textField, len(docs))}
+	for i, doc := range docs {
+		for _, field := range fields {
+			fieldVal, ok := d.lookupField(doc, field)
+			if !ok {
+				continue
+			}
+			weight := float64(idx.weight(field))
+			for _, text := range textValues(fieldVal) {
+				idx.docs[i] = append(idx.docs[i], textField{
+					weight: weight,
+					text:   strings.ToLower(text),
+					scores: scoreTerms(text),
+				})
+			}
+		}
+	}
+	return idx
+}
+
+// weight returns the weight of the field, matching names the same way as filters
+func (idx *textIndex) weight(field string) int {
+	if w, ok := idx.weights[field]; ok {
+		return w
+	}
+	match := matchFieldFunc(field)
+	for name, w := range idx.weights {
+		if match(name) {
+			return w
+		}
+	}
+	return 1
+}
+
+// textValues returns the string or strings held by the value
+func textValues(v reflect.Value) []string {
+	v = indirectValue(v)
+	switch {
+	case v.Kind() == reflect.String:
+		return []string{v.String()}
+	case typeRank(v) == rankArray:
+		var texts []string
+		for i := 0; i < v.Len(); i++ {
+			texts = append(texts, textValues(v.Index(i))...)
+		}
+		return texts
+	}
+	return nil
+}
+
+// scoreTerms scores each term of the text the way MongoDB does. Repeated
+// terms add less each time and terms of short texts score higher
+func scoreTerms(text string) map[string]float64 {
+	type termStats struct {
+		count int
+		freq  float64
+		exp   float64
+	}
+	stats := make(map[string]*termStats)
+	tokens := terms(text)
+	for _, term := range tokens {
+		s, ok := stats[term]
+		if !ok {
+			s = &termStats{exp: 1}
+			stats[term] = s
+		} else {
+			s.exp *= 2
+		}
+		s.count++
+		s.freq += 1 / s.exp
+	}
+
+	scores := make(map[string]float64, len(stats))
+	for term, s := range stats {
+		coeff := 0.5*float64(s.count)/float64(len(tokens)) + 0.5
+		// a field consisting of only the term gets a small boost
+		adjustment := 1.0
+		if strings.EqualFold(strings.TrimSpace(text), term) {
+			adjustment += 0.1
+		}
+		scores[term] = s.freq * coeff * adjustment
+	}
+	return scores
+}
+
+// search returns the matching documents ordered by descending score,
+// documents with an equal score keep their order within the collection
+func (idx *textIndex) search(docs []interface{}, q textQuery) []textResult {
+	var results []textResult
+	for i, fields := range idx.docs {
+		score, ok := q.score(fields)
+		if ok {
+			results = append(results, textResult{data: docs[i], score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+	return results
+}
+
+// score calculates the score of a document, the bool is false when the
+// document doesn't match the query
+func (q textQuery) score(fields []textField) (float64, bool) {
+	for _, phrase := range q.phrases {
+		if !containsPhrase(fields, phrase) {
+			return 0, false
+		}
+	}
+	for _, phrase := range q.negatedPhrases {
+		if containsPhrase(fields, phrase) {
+			return 0, false
+		}
+	}
+	for _, term := range q.negatedTerms {
+		for _, field := range fields {
+			if _, ok := field.scores[term]; ok {
+				return 0, false
+			}
+		}
+	}
+
+	var score float64
+	seen := make(map[string]bool, len(q.terms))
+	for _, term := range q.terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		for _, field := range fields {
+			score += field.weight * field.scores[term]
+		}
+	}
+	// round to avoid float noise deciding the order of equal documents
+	score = math.Round(score*1e9) / 1e9
+	return score, score > 0
+}
+
+func containsPhrase(fields []textField, phrase string) bool {
+	for _, field := range fields {
+		if strings.Contains(field.text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// setScore writes the score of a search result into the field of the document.
+// Structs need a float field with a matching name or bson tag, maps are copied
+// so the stored document is left unchanged
+func setScore(docVal reflect.Value, field string, score float64) {
+	if docVal.Kind() == reflect.Ptr {
+		docVal = docVal.Elem()
+	}
+	match := matchFieldFunc(field)
+	switch docVal.Kind() {
+	case reflect.Struct:
+		t := docVal.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := bsonTagName(t.Field(i))
+			if name == "" {
+				name = t.Field(i).Name
+			}
+			fieldVal := docVal.Field(i)
+			if match(name) && fieldVal.CanSet() {
+				switch fieldVal.Kind() {
+				case reflect.Float32, reflect.Float64:
+					fieldVal.SetFloat(score)
+				}
+				return
+			}
+		}
+	case reflect.Map:
+		scoreVal := reflect.ValueOf(score)
+		if docVal.Type().Key().Kind() != reflect.String || !scoreVal.Type().AssignableTo(docVal.Type().Elem()) {
+			return
+		}
+		copied := reflect.MakeMapWithSize(docVal.Type(), docVal.Len()+1)
+		iter := docVal.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), iter.Value())
+		}
+		copied.SetMapIndex(reflect.ValueOf(field).Convert(docVal.Type().Key()), scoreVal)
+		docVal.Set(copied)
+	}
+}
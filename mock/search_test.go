@@ -0,0 +1,231 @@
+package mock
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_parseTextQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		search string
+		want   textQuery
+	}{
+		{"empty", "  ", textQuery{}},
+		{"terms", "Running dogs", textQuery{terms: []string{"run", "dog"}}},
+		{"stop_words", "the dog and a cat", textQuery{terms: []string{"dog", "cat"}}},
+		{"negated", "dogs -cats", textQuery{terms: []string{"dog"}, negatedTerms: []string{"cat"}}},
+		{"phrase", `"Hot Dogs" mustard`, textQuery{
+			terms:   []string{"hot", "dog", "mustard"},
+			phrases: []string{"hot dogs"},
+		}},
+		{"negated_phrase", `food -"hot dog"`, textQuery{
+			terms:          []string{"food"},
+			negatedPhrases: []string{"hot dog"},
+		}},
+		{"unterminated_phrase", `"hot dog`, textQuery{terms: []string{"hot", "dog"}, phrases: []string{"hot dog"}}},
+		{"punctuation", "dogs, cats!", textQuery{terms: []string{"dog", "cat"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTextQuery(tt.search); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTextQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type article struct {
+	Title string
+	Body  string
+	Tags  []string
+}
+
+func TestDB_Search_Relevance(t *testing.T) {
+	t.Parallel()
+	articles := []article{
+		{Title: "Cooking pasta", Body: "Boil the water and cook the pasta until it is soft"},
+		{Title: "Dogs", Body: "Walking dogs every day keeps them healthy"},
+		{Title: "Hot dogs", Body: "A recipe for hot dogs with mustard", Tags: []string{"cooking"}},
+		{Title: "Cats and dogs", Body: "Why cats and dogs fight, and how dogs learn"},
+	}
+	d := CreateDB()
+	for _, a := range articles {
+		if err := d.Insert("articles", a); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+	d.SetTextIndex("articles", map[string]int{"title": 10, "body": 1, "tags": 5})
+
+	tests := []struct {
+		name    string
+		search  string
+		fields  []string
+		want    []string
+		wantErr bool
+	}{
+		{"weighted_title", "dog", nil, []string{"Dogs", "Cats and dogs", "Hot dogs"}, false},
+		{"body_only", "dog", []string{"body"}, []string{"Cats and dogs", "Hot dogs", "Dogs"}, false},
+		{"stemmed", "cooked", nil, []string{"Cooking pasta", "Hot dogs"}, false},
+		{"phrase", `"hot dogs"`, nil, []string{"Hot dogs"}, false},
+		{"negation", "dogs -cats", nil, []string{"Dogs", "Hot dogs"}, false},
+		{"negated_phrase", `dogs -"hot dogs"`, nil, []string{"Dogs", "Cats and dogs"}, false},
+		{"only_negation", "-cats", nil, nil, false},
+		{"stop_words_only", "the and", nil, nil, false},
+		{"no_match", "elephant", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []article
+			if err := d.Search("articles", tt.search, tt.fields, &got); (err != nil) != tt.wantErr {
+				t.Fatalf("DB.Search() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var titles []string
+			for _, a := range got {
+				titles = append(titles, a.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("DB.Search() = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+}
+
+func TestDB_Search_Errors(t *testing.T) {
+	t.Parallel()
+	d := CreateDB()
+	if err := d.Insert("col", article{Title: "foo"}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	tests := []struct {
+		name       string
+		collection string
+		fields     []string
+		slice      interface{}
+	}{
+		{"no_collection", "missing", []string{"title"}, &[]article{}},
+		{"no_fields", "col", nil, &[]article{}},
+		{"not_pointer", "col", []string{"title"}, []article{}},
+		{"not_slice", "col", []string{"title"}, &article{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := d.Search(tt.collection, "foo", tt.fields, tt.slice); err == nil {
+				t.Errorf("DB.Search() expected error")
+			}
+		})
+	}
+}
+
+func Test_scoreTerms(t *testing.T) {
+	short := scoreTerms("dogs")
+	long := scoreTerms("dogs are walking in the park")
+	repeated := scoreTerms("dogs dogs dogs park")
+	if short["dog"] <= long["dog"] {
+		t.Errorf("short text scored %v, less than long text %v", short["dog"], long["dog"])
+	}
+	if repeated["dog"] <= repeated["park"] {
+		t.Errorf("repeated term scored %v, less than single term %v", repeated["dog"], repeated["park"])
+	}
+	if repeated["dog"] >= 3*repeated["park"] {
+		t.Errorf("repeated term scored %v, repeats should add less each time", repeated["dog"])
+	}
+}
+
+type scoredArticle struct {
+	Title string
+	Body  string
+	Tags  []string
+	Score float64 `bson:"score"`
+}
+
+func TestDB_SearchWithOptions(t *testing.T) {
+	t.Parallel()
+	articles := []article{
+		{Title: "dogs", Tags: []string{"pets"}},
+		{Title: "dogs and cats", Tags: []string{"pets"}},
+		{Title: "hot dogs", Tags: []string{"food"}},
+		{Title: "dogs dogs dogs", Tags: []string{"pets"}},
+	}
+	for name, d := range map[string]*DB{"go": CreateDB(), "bson": CreateBSONDB(nil)} {
+		d := d
+		for _, a := range articles {
+			if err := d.Insert("articles", a); err != nil {
+				t.Fatal("error inserting:", err)
+			}
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			tests := []struct {
+				name   string
+				filter *db.Filter
+				opts   *db.Options
+				want   []string
+			}{
+				{"nil", nil, nil, []string{"dogs dogs dogs", "dogs", "dogs and cats", "hot dogs"}},
+				{"filter", &db.Filter{"tags": "pets"}, nil, []string{"dogs dogs dogs", "dogs", "dogs and cats"}},
+				{"skip_limit", nil, db.CreateOptions().SetSkip(1).SetLimit(2), []string{"dogs", "dogs and cats"}},
+				{"sort", &db.Filter{"tags": "pets"}, db.CreateOptions().SetSort("title", 1),
+					[]string{"dogs", "dogs and cats", "dogs dogs dogs"}},
+			}
+			for _, tt := range tests {
+				var got []article
+				if err := d.SearchWithOptions("articles", "dog", []string{"title"}, &got, tt.filter, tt.opts); err != nil {
+					t.Fatalf("%v: DB.SearchWithOptions() error: %v", tt.name, err)
+				}
+				var titles []string
+				for _, a := range got {
+					titles = append(titles, a.Title)
+				}
+				if !reflect.DeepEqual(titles, tt.want) {
+					t.Errorf("%v: DB.SearchWithOptions() = %v, want %v", tt.name, titles, tt.want)
+				}
+			}
+
+			var scored []*scoredArticle
+			opts := db.CreateOptions().SetScoreField("score")
+			if err := d.SearchWithOptions("articles", "dog", []string{"title"}, &scored, nil, opts); err != nil {
+				t.Fatal("DB.SearchWithOptions() error:", err)
+			}
+			for i, a := range scored {
+				if a.Score <= 0 || (i > 0 && a.Score > scored[i-1].Score) {
+					t.Errorf("DB.SearchWithOptions() score not set in order: %v", scored)
+				}
+			}
+
+			var maps []bson.M
+			if err := d.SearchWithOptions("articles", "cats", []string{"title"}, &maps, nil, opts); err != nil {
+				t.Fatal("DB.SearchWithOptions() error:", err)
+			}
+			if len(maps) != 1 || maps[0]["score"] == nil {
+				t.Errorf("DB.SearchWithOptions() score not set in map: %v", maps)
+			}
+		})
+	}
+}
+
+func Test_setScore(t *testing.T) {
+	stored := bson.M{"title": "foo"}
+	docs := []interface{}{stored, &scoredArticle{}, article{}, map[int]string{}}
+	for _, doc := range docs {
+		docVal := reflect.New(reflect.TypeOf(doc)).Elem()
+		docVal.Set(reflect.ValueOf(doc))
+		setScore(docVal, "score", 1.5)
+		switch v := docVal.Interface().(type) {
+		case bson.M:
+			if v["score"] != 1.5 {
+				t.Errorf("setScore() map = %v", v)
+			}
+		case *scoredArticle:
+			if v.Score != 1.5 {
+				t.Errorf("setScore() struct = %v", v)
+			}
+		}
+	}
+	if _, ok := stored["score"]; ok {
+		t.Errorf("setScore() modified the stored map")
+	}
+}
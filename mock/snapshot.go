@@ -0,0 +1,127 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Snapshot is the state of the collections of a DB at the time it was taken,
+// it shares the stored documents with the DB until either of them is changed
+type Snapshot struct {
+	collections map[string][]interface{}
+	textIndices map[string]map[string]int
+	bson        bool
+}
+
+// Snapshot captures the documents and text indices of every collection,
+// it only copies the collection headers so it can be taken for each test case
+func (d *DB) Snapshot() *Snapshot {
+	d.Lock()
+	defer d.Unlock()
+	s := &Snapshot{
+		collections: make(map[string][]interface{}, len(d.collectionMap)),
+		textIndices: make(map[string]map[string]int, len(d.textIndices)),
+		bson:        d.registry != nil,
+	}
+	for collection, dataSlice := range d.collectionMap {
+		s.collections[collection] = *dataSlice
+		d.share(collection)
+	}
+	for collection, weights := range d.textIndices {
+		s.textIndices[collection] = weights
+	}
+	return s
+}
+
+// Restore replaces every collection of the DB with the ones of the snapshot,
+// a snapshot can be restored any number of times
+func (d *DB) Restore(s *Snapshot) error {
+	if s == nil {
+		return errors.New("mock.DB.Restore() error: snapshot is nil")
+	}
+	d.Lock()
+	defer d.Unlock()
+	if s.bson != (d.registry != nil) {
+		return errors.New("mock.DB.Restore() error: snapshot was taken from a DB with a different storage mode")
+	}
+	d.collectionMap = make(map[string]*[]interface{}, len(s.collections))
+	d.shared = nil
+	for collection, data := range s.collections {
+		dataSlice := data
+		d.collectionMap[collection] = &dataSlice
+		d.share(collection)
+	}
+	d.textIndices = make(map[string]map[string]int, len(s.textIndices))
+	for collection, weights := range s.textIndices {
+		d.textIndices[collection] = weights
+	}
+	if d.store != nil {
+		if err := d.compact(); err != nil {
+			return fmt.Errorf("mock.DB.Restore() error: %v", err)
+		}
+	}
+	return nil
+}
+
+// Drop removes the collection along with its text index
+func (d *DB) Drop(collection string) error {
+	if err := d.record(Call{Method: "Drop", Collection: collection}); err != nil {
+		return err
+	}
+	d.Lock()
+	defer d.Unlock()
+	if err := d.checkClosed("Drop"); err != nil {
+		return err
+	}
+	if err := d.persist(logDrop, collection, 0, nil); err != nil {
+		return fmt.Errorf("mock.DB.Drop() error: %v", err)
+	}
+	delete(d.collectionMap, collection)
+	delete(d.textIndices, collection)
+	delete(d.shared, collection)
+	return nil
+}
+
+// Reset removes every document of the collection, keeping its text index
+func (d *DB) Reset(collection string) error {
+	if err := d.record(Call{Method: "Reset", Collection: collection}); err != nil {
+		return err
+	}
+	d.Lock()
+	defer d.Unlock()
+	if err := d.checkClosed("Reset"); err != nil {
+		return err
+	}
+	if d.collectionMap[collection] == nil {
+		return nil
+	}
+	if err := d.persist(logReset, collection, 0, nil); err != nil {
+		return fmt.Errorf("mock.DB.Reset() error: %v", err)
+	}
+	d.collectionMap[collection] = &[]interface{}{}
+	delete(d.shared, collection)
+	return nil
+}
+
+// share marks the documents of the collection as shared with a snapshot,
+// the caller must hold the lock of the DB
+func (d *DB) share(collection string) {
+	if d.shared == nil {
+		d.shared = make(map[string]bool)
+	}
+	d.shared[collection] = true
+}
+
+// own returns the documents of the collection after copying them if they are
+// shared with a snapshot, the caller must hold the lock of the DB
+func (d *DB) own(collection string) *[]interface{} {
+	dataSlice := d.collectionMap[collection]
+	if dataSlice == nil || !d.shared[collection] {
+		return dataSlice
+	}
+	owned := make([]interface{}, len(*dataSlice))
+	copy(owned, *dataSlice)
+	d.collectionMap[collection] = &owned
+	delete(d.shared, collection)
+	return &owned
+}
@@ -0,0 +1,146 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+func TestDB_Snapshot_Restore(t *testing.T) {
+	t.Parallel()
+	for name, d := range map[string]*DB{"go": CreateDB(), "bson": CreateBSONDB(nil)} {
+		d := d
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			for i := 1; i <= 3; i++ {
+				if err := d.Insert("col", testObj{Name: "obj", Value: i}); err != nil {
+					t.Fatal("error inserting:", err)
+				}
+			}
+			d.SetTextIndex("col", map[string]int{"name": 2})
+			snapshot := d.Snapshot()
+			want := []testObj{{Name: "obj", Value: 1}, {Name: "obj", Value: 2}, {Name: "obj", Value: 3}}
+
+			tests := []struct {
+				name   string
+				change func() error
+			}{
+				{"insert", func() error { return d.Insert("col", testObj{Value: 4}) }},
+				{"update", func() error { return d.Update("col", testObj{Value: 5}, &db.Filter{"value": 1}) }},
+				{"upsert", func() error { return d.Upsert("col", testObj{Value: 6}, &db.Filter{"value": 2}) }},
+				{"delete", func() error { return d.Delete("col", &db.Filter{"value": 1}) }},
+				{"new_collection", func() error { return d.Insert("other", testObj{}) }},
+				{"drop", func() error { return d.Drop("col") }},
+				{"reset", func() error { return d.Reset("col") }},
+				{"open", func() error { return d.Open(context.Background()) }},
+			}
+			for _, tt := range tests {
+				if err := tt.change(); err != nil {
+					t.Fatalf("%v: error changing: %v", tt.name, err)
+				}
+				if err := d.Restore(snapshot); err != nil {
+					t.Fatalf("%v: DB.Restore() error: %v", tt.name, err)
+				}
+				var got []testObj
+				if err := d.FindAll("col", &got, nil, nil); err != nil {
+					t.Fatalf("%v: DB.FindAll() error: %v", tt.name, err)
+				}
+				if !sliceDeepEqual(&got, &want) {
+					t.Errorf("%v: DB.FindAll() after restore = %v, want %v", tt.name, got, want)
+				}
+				var other []testObj
+				if err := d.FindAll("other", &other, nil, nil); err == nil {
+					t.Errorf("%v: DB.FindAll() found collection created after the snapshot", tt.name)
+				}
+				if d.textIndices["col"]["name"] != 2 {
+					t.Errorf("%v: text index not restored: %v", tt.name, d.textIndices)
+				}
+			}
+		})
+	}
+}
+
+func TestDB_Snapshot_Independent(t *testing.T) {
+	d := CreateDB()
+	if err := d.Insert("col", testObj{Value: 1}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	first := d.Snapshot()
+	if err := d.Insert("col", testObj{Value: 2}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	second := d.Snapshot()
+
+	// appending after restoring the first snapshot must not overwrite the second
+	if err := d.Restore(first); err != nil {
+		t.Fatal("DB.Restore() error:", err)
+	}
+	if err := d.Insert("col", testObj{Value: 3}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	if err := d.Restore(second); err != nil {
+		t.Fatal("DB.Restore() error:", err)
+	}
+	var got []testObj
+	if err := d.FindAll("col", &got, nil, nil); err != nil {
+		t.Fatal("DB.FindAll() error:", err)
+	}
+	want := []testObj{{Value: 1}, {Value: 2}}
+	if !sliceDeepEqual(&got, &want) {
+		t.Errorf("DB.FindAll() = %v, want %v", got, want)
+	}
+}
+
+func TestDB_Restore_Errors(t *testing.T) {
+	if err := CreateDB().Restore(nil); err == nil {
+		t.Errorf("DB.Restore() expected error for nil snapshot")
+	}
+	if err := CreateBSONDB(nil).Restore(CreateDB().Snapshot()); err == nil {
+		t.Errorf("DB.Restore() expected error for different storage mode")
+	}
+}
+
+func TestDB_Drop_Reset(t *testing.T) {
+	d := CreateDB()
+	for _, col := range []string{"dropped", "reset"} {
+		if err := d.Insert(col, testObj{}); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+		d.SetTextIndex(col, map[string]int{"name": 1})
+	}
+	if err := d.Drop("dropped"); err != nil {
+		t.Fatal("DB.Drop() error:", err)
+	}
+	if err := d.Reset("reset"); err != nil {
+		t.Fatal("DB.Reset() error:", err)
+	}
+
+	var got []testObj
+	if err := d.FindAll("dropped", &got, nil, nil); err == nil {
+		t.Errorf("DB.FindAll() found dropped collection")
+	}
+	if _, ok := d.textIndices["dropped"]; ok {
+		t.Errorf("text index of dropped collection kept")
+	}
+	if err := d.FindAll("reset", &got, nil, nil); err != nil || len(got) != 0 {
+		t.Errorf("DB.FindAll() on reset collection = %v, %v", got, err)
+	}
+	if _, ok := d.textIndices["reset"]; !ok {
+		t.Errorf("text index of reset collection removed")
+	}
+	d.Expect().Drop("dropped").Times(1)
+	d.Expect().Reset("reset").Times(1)
+	d.Verify(t)
+
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatal("DB.Close() error:", err)
+	}
+	if err := d.Drop("reset"); !errors.Is(err, db.ErrClosed) {
+		t.Errorf("DB.Drop() error = %v, want db.ErrClosed", err)
+	}
+	if err := d.Reset("reset"); !errors.Is(err, db.ErrClosed) {
+		t.Errorf("DB.Reset() error = %v, want db.ErrClosed", err)
+	}
+}
@@ -0,0 +1,236 @@
+package mock
+
+import "strings"
+
+// stem reduces an english word to its stem using the Porter stemming
+// algorithm, so "connected", "connecting" and "connection" all become "connect".
+// Words which are not lower case ascii are returned unchanged
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	s := &stemmer{b: []byte(word)}
+	s.step1a()
+	s.step1b()
+	s.step1c()
+	s.step2()
+	s.step3()
+	s.step4()
+	s.step5()
+	return string(s.b)
+}
+
+type stemmer struct {
+	b []byte
+}
+
+// isConsonant reports whether the letter at i is a consonant,
+// y is a consonant when it follows a vowel or starts the word
+func (s *stemmer) isConsonant(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.isConsonant(i-1)
+	}
+	return true
+}
+
+// measure counts the vowel consonant sequences within the first n letters
+func (s *stemmer) measure(n int) int {
+	m, i := 0, 0
+	for i < n && s.isConsonant(i) {
+		i++
+	}
+	for i < n {
+		for i < n && !s.isConsonant(i) {
+			i++
+		}
+		if i >= n {
+			break
+		}
+		for i < n && s.isConsonant(i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+// hasVowel reports whether the first n letters contain a vowel
+func (s *stemmer) hasVowel(n int) bool {
+	for i := 0; i < n; i++ {
+		if !s.isConsonant(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsDoubleConsonant reports whether the first n letters end with a double consonant
+func (s *stemmer) endsDoubleConsonant(n int) bool {
+	return n >= 2 && s.b[n-1] == s.b[n-2] && s.isConsonant(n-1)
+}
+
+// endsCVC reports whether the first n letters end consonant, vowel, consonant
+// where the last consonant is not w, x or y
+func (s *stemmer) endsCVC(n int) bool {
+	if n < 3 || !s.isConsonant(n-1) || s.isConsonant(n-2) || !s.isConsonant(n-3) {
+		return false
+	}
+	switch s.b[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func (s *stemmer) hasSuffix(suffix string) bool {
+	return strings.HasSuffix(string(s.b), suffix)
+}
+
+// replace replaces the suffix with replacement
+func (s *stemmer) replace(suffix, replacement string) {
+	s.b = append(s.b[:len(s.b)-len(suffix)], replacement...)
+}
+
+// replaceRules replaces the first matching suffix when its stem has a measure
+// greater than min. Only the first matching suffix is considered
+func (s *stemmer) replaceRules(rules [][2]string, min int) {
+	for _, rule := range rules {
+		if s.hasSuffix(rule[0]) {
+			if s.measure(len(s.b)-len(rule[0])) > min {
+				s.replace(rule[0], rule[1])
+			}
+			return
+		}
+	}
+}
+
+func (s *stemmer) step1a() {
+	switch {
+	case s.hasSuffix("sses"):
+		s.replace("sses", "ss")
+	case s.hasSuffix("ies"):
+		s.replace("ies", "i")
+	case s.hasSuffix("ss"):
+	case s.hasSuffix("s"):
+		s.replace("s", "")
+	}
+}
+
+func (s *stemmer) step1b() {
+	if s.hasSuffix("eed") {
+		if s.measure(len(s.b)-3) > 0 {
+			s.replace("eed", "ee")
+		}
+		return
+	}
+	removed := false
+	for _, suffix := range []string{"ed", "ing"} {
+		if s.hasSuffix(suffix) && s.hasVowel(len(s.b)-len(suffix)) {
+			s.replace(suffix, "")
+			removed = true
+			break
+		}
+	}
+	if !removed {
+		return
+	}
+	n := len(s.b)
+	switch {
+	case s.hasSuffix("at"), s.hasSuffix("bl"), s.hasSuffix("iz"):
+		s.b = append(s.b, 'e')
+	case s.endsDoubleConsonant(n):
+		switch s.b[n-1] {
+		case 'l', 's', 'z':
+		default:
+			s.b = s.b[:n-1]
+		}
+	case s.measure(n) == 1 && s.endsCVC(n):
+		s.b = append(s.b, 'e')
+	}
+}
+
+func (s *stemmer) step1c() {
+	if s.hasSuffix("y") && s.hasVowel(len(s.b)-1) {
+		s.replace("y", "i")
+	}
+}
+
+var step2Rules = longestFirst([][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"},
+	{"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"},
+	{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"},
+	{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}, {"logi", "log"},
+})
+
+func (s *stemmer) step2() {
+	s.replaceRules(step2Rules, 0)
+}
+
+var step3Rules = longestFirst([][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+})
+
+func (s *stemmer) step3() {
+	s.replaceRules(step3Rules, 0)
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func (s *stemmer) step4() {
+	var match string
+	for _, suffix := range step4Suffixes {
+		if s.hasSuffix(suffix) && len(suffix) > len(match) {
+			match = suffix
+		}
+	}
+	if match == "" {
+		return
+	}
+	n := len(s.b) - len(match)
+	if s.measure(n) <= 1 {
+		return
+	}
+	if match == "ion" && (n == 0 || (s.b[n-1] != 's' && s.b[n-1] != 't')) {
+		return
+	}
+	s.b = s.b[:n]
+}
+
+func (s *stemmer) step5() {
+	if s.hasSuffix("e") {
+		n := len(s.b) - 1
+		if m := s.measure(n); m > 1 || (m == 1 && !s.endsCVC(n)) {
+			s.b = s.b[:n]
+		}
+	}
+	n := len(s.b)
+	if n > 0 && s.b[n-1] == 'l' && s.endsDoubleConsonant(n) && s.measure(n) > 1 {
+		s.b = s.b[:n-1]
+	}
+}
+
+// longestFirst orders the rules by the length of their suffix,
+// so the longest matching suffix is found first
+func longestFirst(rules [][2]string) [][2]string {
+	sorted := make([][2]string, len(rules))
+	copy(sorted, rules)
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && len(sorted[j][0]) > len(sorted[j-1][0]); j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,46 @@
+package mock
+
+import "testing"
+
+func Test_stem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"a", "a"},
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"cats", "cat"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"hopping", "hop"},
+		{"falling", "fall"},
+		{"filing", "file"},
+		{"happy", "happi"},
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"generalizations", "gener"},
+		{"connection", "connect"},
+		{"connected", "connect"},
+		{"adjustment", "adjust"},
+		{"adoption", "adopt"},
+		{"effective", "effect"},
+		{"goodness", "good"},
+		{"hopeful", "hope"},
+		{"probate", "probat"},
+		{"rate", "rate"},
+		{"controll", "control"},
+		{"running", "run"},
+		{"searches", "search"},
+		{"éclair", "éclair"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := stem(tt.word); got != tt.want {
+				t.Errorf("stem(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
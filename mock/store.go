@@ -0,0 +1,361 @@
+package mock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+)
+
+const (
+	snapshotFile = "snapshot.bson"
+	logFile      = "log.bson"
+	// compactThreshold is the number of log records written before compacting
+	compactThreshold = 1000
+)
+
+// operations written to the log
+const (
+	logInsert = "insert"
+	logUpdate = "update"
+	logDelete = "delete"
+	logDrop   = "drop"
+	logReset  = "reset"
+)
+
+// logRecord is a change written to the append-only log, documents are addressed
+// by their position within the collection which replaying reproduces exactly
+type logRecord struct {
+	Seq        int64    `bson:"seq"`
+	Op         string   `bson:"op"`
+	Collection string   `bson:"c"`
+	Index      int      `bson:"i"`
+	Document   bson.Raw `bson:"d,omitempty"`
+}
+
+// snapshotHeader is the first document of the snapshot file, log records
+// up to seq are already applied to the snapshot
+type snapshotHeader struct {
+	Seq int64 `bson:"seq"`
+}
+
+// snapshotCollection holds every document of a collection within the snapshot file
+type snapshotCollection struct {
+	Collection string     `bson:"c"`
+	Documents  []bson.Raw `bson:"docs"`
+}
+
+// fileStore persists the collections of a DB within a directory as a snapshot
+// of every collection and a log of the changes made since
+type fileStore struct {
+	dir string
+	log *os.File
+	// seq is the sequence number of the last log record
+	seq int64
+	// records is the number of records within the log
+	records          int
+	compactThreshold int
+}
+
+// CreateFileDB creates a DB in BSON mode which persists its collections within
+// dir and loads the ones already stored there. Every change is appended and
+// synced to a log, which is compacted into a snapshot every 1000 changes and
+// on Close. Open reloads the collections after Close
+func CreateFileDB(dir string, registry *bsoncodec.Registry) (*DB, error) {
+	d := CreateBSONDB(registry)
+	d.store = &fileStore{dir: dir, compactThreshold: compactThreshold}
+	if err := d.load(); err != nil {
+		return nil, fmt.Errorf("mock.CreateFileDB() error: %v", err)
+	}
+	return d, nil
+}
+
+// load reads the snapshot, replays the log on top of it and opens the log
+// for appending, the caller must hold the lock of the DB
+func (d *DB) load() error {
+	s := d.store
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	d.collectionMap = make(map[string]*[]interface{})
+	d.shared = nil
+	s.seq, s.records = 0, 0
+
+	if err := d.readSnapshot(); err != nil {
+		return fmt.Errorf("error reading snapshot: %v", err)
+	}
+
+	log, err := os.OpenFile(filepath.Join(s.dir, logFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	valid, err := d.replay(log)
+	if err == nil {
+		// drop a record torn by a crash so new records are appended after valid ones
+		err = log.Truncate(valid)
+	}
+	if err == nil {
+		_, err = log.Seek(valid, io.SeekStart)
+	}
+	if err != nil {
+		log.Close()
+		return fmt.Errorf("error reading log: %v", err)
+	}
+	s.log = log
+	return nil
+}
+
+func (d *DB) readSnapshot() error {
+	f, err := os.Open(filepath.Join(d.store.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header, err := readDocument(f)
+	if err != nil {
+		return err
+	}
+	var h snapshotHeader
+	if err := bson.Unmarshal(header, &h); err != nil {
+		return err
+	}
+	d.store.seq = h.Seq
+
+	for {
+		doc, err := readDocument(f)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var col snapshotCollection
+		if err := bson.Unmarshal(doc, &col); err != nil {
+			return err
+		}
+		data := make([]interface{}, len(col.Documents))
+		for i, raw := range col.Documents {
+			data[i] = raw
+		}
+		d.collectionMap[col.Collection] = &data
+	}
+}
+
+// replay applies the records of the log newer than the snapshot and returns
+// the offset of the end of the last complete record
+func (d *DB) replay(log *os.File) (int64, error) {
+	var valid int64
+	for {
+		doc, err := readDocument(log)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return valid, nil
+		}
+		if err != nil {
+			return valid, err
+		}
+		var r logRecord
+		if err := bson.Unmarshal(doc, &r); err != nil {
+			return valid, err
+		}
+		valid += int64(len(doc))
+		d.store.records++
+		if r.Seq <= d.store.seq {
+			continue
+		}
+		if err := d.apply(r); err != nil {
+			return valid, fmt.Errorf("record %d: %v", r.Seq, err)
+		}
+		d.store.seq = r.Seq
+	}
+}
+
+// apply makes the change of the log record to the collections
+func (d *DB) apply(r logRecord) error {
+	dataSlice := d.collectionMap[r.Collection]
+	switch r.Op {
+	case logInsert:
+		if dataSlice == nil {
+			dataSlice = &[]interface{}{}
+			d.collectionMap[r.Collection] = dataSlice
+		}
+		*dataSlice = append(*dataSlice, r.Document)
+		return nil
+	case logDrop:
+		delete(d.collectionMap, r.Collection)
+		return nil
+	case logReset:
+		if dataSlice != nil {
+			d.collectionMap[r.Collection] = &[]interface{}{}
+		}
+		return nil
+	}
+	if dataSlice == nil || r.Index < 0 || r.Index >= len(*dataSlice) {
+		return fmt.Errorf("document %d of %v does not exist", r.Index, r.Collection)
+	}
+	switch r.Op {
+	case logUpdate:
+		(*dataSlice)[r.Index] = r.Document
+	case logDelete:
+		*dataSlice = append((*dataSlice)[:r.Index], (*dataSlice)[r.Index+1:]...)
+	default:
+		return fmt.Errorf("unknown operation %q", r.Op)
+	}
+	return nil
+}
+
+// persist appends the change to the log before it is made to the collections,
+// compacting the log first once it reaches the threshold. The caller must hold
+// the lock of the DB
+func (d *DB) persist(op, collection string, index int, data interface{}) error {
+	s := d.store
+	if s == nil {
+		return nil
+	}
+	if s.log == nil {
+		return errors.New("mock.DB is closed")
+	}
+	// compact before writing as the changes of the log are all made by now
+	if s.records >= s.compactThreshold {
+		if err := d.compact(); err != nil {
+			return err
+		}
+	}
+	r := logRecord{Seq: s.seq + 1, Op: op, Collection: collection, Index: index}
+	if raw, ok := data.(bson.Raw); ok {
+		r.Document = raw
+	}
+	doc, err := bson.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.log.Write(doc); err != nil {
+		return fmt.Errorf("error writing log: %v", err)
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("error syncing log: %v", err)
+	}
+	s.seq++
+	s.records++
+	return nil
+}
+
+// compact writes every collection to a new snapshot, replacing the old one
+// with a rename once it is synced, and then empties the log. The snapshot holds
+// the sequence number of the last change so a crash before the log is emptied
+// does not replay changes twice. The caller must hold the lock of the DB
+func (d *DB) compact() error {
+	s := d.store
+	if s.log == nil {
+		return errors.New("mock.DB is closed")
+	}
+	tmp, err := ioutil.TempFile(s.dir, snapshotFile+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = writeDocument(tmp, snapshotHeader{Seq: s.seq})
+	for collection, dataSlice := range d.collectionMap {
+		if err != nil {
+			break
+		}
+		col := snapshotCollection{Collection: collection, Documents: make([]bson.Raw, len(*dataSlice))}
+		for i, data := range *dataSlice {
+			col.Documents[i] = data.(bson.Raw)
+		}
+		err = writeDocument(tmp, col)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, snapshotFile))
+	}
+	if err == nil {
+		err = syncDir(s.dir)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing snapshot: %v", err)
+	}
+
+	if err := s.log.Truncate(0); err != nil {
+		return fmt.Errorf("error truncating log: %v", err)
+	}
+	if _, err := s.log.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error truncating log: %v", err)
+	}
+	s.records = 0
+	return s.log.Sync()
+}
+
+// closeStore compacts the collections and closes the log,
+// the caller must hold the lock of the DB
+func (d *DB) closeStore() error {
+	s := d.store
+	if s == nil || s.log == nil {
+		return nil
+	}
+	err := d.compact()
+	if closeErr := s.log.Close(); err == nil {
+		err = closeErr
+	}
+	s.log = nil
+	return err
+}
+
+// readDocument reads a single BSON document, it returns io.EOF at the end of
+// the reader and io.ErrUnexpectedEOF when the document is incomplete
+func readDocument(r io.Reader) (bson.Raw, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.LittleEndian.Uint32(length[:]))
+	if size < 5 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	doc := make([]byte, size)
+	copy(doc, length[:])
+	if _, err := io.ReadFull(r, doc[4:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if err := bson.Raw(doc).Validate(); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return doc, nil
+}
+
+func writeDocument(w io.Writer, v interface{}) error {
+	doc, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(doc)
+	return err
+}
+
+// syncDir syncs the directory so a rename within it is durable
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,229 @@
+package mock
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// fillFileDB makes every kind of change persisted by a file DB
+func fillFileDB(t *testing.T, d *DB) {
+	t.Helper()
+	for i := 0; i < 5; i++ {
+		if err := d.Insert("users", taggedObj{ID: string(rune('a' + i)), Desc: "user"}); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+		if err := d.Insert("dropped", testObj{Value: i}); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+		if err := d.Insert("reset", testObj{Value: i}); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+	if err := d.Update("users", taggedObj{ID: "b", Desc: "updated"}, &db.Filter{"_id": "b"}); err != nil {
+		t.Fatal("error updating:", err)
+	}
+	if err := d.Upsert("users", taggedObj{ID: "z", Desc: "upserted"}, &db.Filter{"_id": "z"}); err != nil {
+		t.Fatal("error upserting:", err)
+	}
+	if err := d.Delete("users", &db.Filter{"_id": "c"}); err != nil {
+		t.Fatal("error deleting:", err)
+	}
+	if err := d.Drop("dropped"); err != nil {
+		t.Fatal("error dropping:", err)
+	}
+	if err := d.Reset("reset"); err != nil {
+		t.Fatal("error resetting:", err)
+	}
+}
+
+func dump(t *testing.T, d *DB) string {
+	t.Helper()
+	data, err := d.Dump()
+	if err != nil {
+		t.Fatal("DB.Dump() error:", err)
+	}
+	return string(data)
+}
+
+// crash closes the log of the DB without compacting, as if the process died
+func crash(d *DB) {
+	d.store.log.Close()
+	d.store.log = nil
+}
+
+func TestCreateFileDB_Reload(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		reopen    func(t *testing.T, d *DB, dir string) *DB
+	}{
+		{"crash", compactThreshold, func(t *testing.T, d *DB, dir string) *DB {
+			crash(d)
+			reloaded, err := CreateFileDB(dir, nil)
+			if err != nil {
+				t.Fatal("CreateFileDB() error:", err)
+			}
+			return reloaded
+		}},
+		{"compacted_crash", 4, func(t *testing.T, d *DB, dir string) *DB {
+			crash(d)
+			reloaded, err := CreateFileDB(dir, nil)
+			if err != nil {
+				t.Fatal("CreateFileDB() error:", err)
+			}
+			return reloaded
+		}},
+		{"close_open", compactThreshold, func(t *testing.T, d *DB, dir string) *DB {
+			if err := d.Close(context.Background()); err != nil {
+				t.Fatal("DB.Close() error:", err)
+			}
+			if err := d.Insert("users", taggedObj{ID: "closed"}); err == nil {
+				t.Errorf("DB.Insert() expected error after close")
+			}
+			if err := d.Open(context.Background()); err != nil {
+				t.Fatal("DB.Open() error:", err)
+			}
+			return d
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			d, err := CreateFileDB(dir, nil)
+			if err != nil {
+				t.Fatal("CreateFileDB() error:", err)
+			}
+			d.store.compactThreshold = tt.threshold
+			fillFileDB(t, d)
+			want := dump(t, d)
+
+			reloaded := tt.reopen(t, d, dir)
+			if got := dump(t, reloaded); got != want {
+				t.Errorf("reloaded DB = %v, want %v", got, want)
+			}
+			// changes after reloading are appended to the same log
+			if err := reloaded.Insert("users", taggedObj{ID: "new"}); err != nil {
+				t.Fatal("error inserting:", err)
+			}
+			want = dump(t, reloaded)
+			crash(reloaded)
+			again, err := CreateFileDB(dir, nil)
+			if err != nil {
+				t.Fatal("CreateFileDB() error:", err)
+			}
+			if got := dump(t, again); got != want {
+				t.Errorf("reloaded DB = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCreateFileDB_Compact(t *testing.T) {
+	dir := t.TempDir()
+	d, err := CreateFileDB(dir, nil)
+	if err != nil {
+		t.Fatal("CreateFileDB() error:", err)
+	}
+	d.store.compactThreshold = 3
+	for i := 0; i < 7; i++ {
+		if err := d.Insert("col", testObj{Value: i}); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+	if d.store.records != 1 {
+		t.Errorf("log holds %d records after compacting, want 1", d.store.records)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); err != nil {
+		t.Errorf("snapshot not written: %v", err)
+	}
+
+	// a crash between writing the snapshot and emptying the log leaves
+	// records already within the snapshot, which must not be replayed
+	oldLog, err := ioutil.ReadFile(filepath.Join(dir, logFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dump(t, d)
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatal("DB.Close() error:", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, logFile), oldLog, 0644); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := CreateFileDB(dir, nil)
+	if err != nil {
+		t.Fatal("CreateFileDB() error:", err)
+	}
+	if got := dump(t, reloaded); got != want {
+		t.Errorf("reloaded DB = %v, want %v", got, want)
+	}
+}
+
+func TestCreateFileDB_TornLog(t *testing.T) {
+	dir := t.TempDir()
+	d, err := CreateFileDB(dir, nil)
+	if err != nil {
+		t.Fatal("CreateFileDB() error:", err)
+	}
+	if err := d.Insert("col", testObj{Name: "kept"}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	want := dump(t, d)
+	// a record cut short by a crash
+	if _, err := d.store.log.Write([]byte{200, 0, 0, 0, 3, 'o', 'p'}); err != nil {
+		t.Fatal(err)
+	}
+	crash(d)
+
+	reloaded, err := CreateFileDB(dir, nil)
+	if err != nil {
+		t.Fatal("CreateFileDB() error:", err)
+	}
+	if got := dump(t, reloaded); got != want {
+		t.Errorf("reloaded DB = %v, want %v", got, want)
+	}
+	if err := reloaded.Insert("col", testObj{Name: "appended"}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	want = dump(t, reloaded)
+	crash(reloaded)
+	again, err := CreateFileDB(dir, nil)
+	if err != nil {
+		t.Fatal("CreateFileDB() error:", err)
+	}
+	if got := dump(t, again); got != want {
+		t.Errorf("reloaded DB = %v, want %v", got, want)
+	}
+}
+
+func TestCreateFileDB_Restore(t *testing.T) {
+	dir := t.TempDir()
+	d, err := CreateFileDB(dir, nil)
+	if err != nil {
+		t.Fatal("CreateFileDB() error:", err)
+	}
+	if err := d.Insert("col", testObj{Name: "snapshot"}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	snapshot := d.Snapshot()
+	want := dump(t, d)
+	if err := d.Insert("col", testObj{Name: "discarded"}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	if err := d.Restore(snapshot); err != nil {
+		t.Fatal("DB.Restore() error:", err)
+	}
+	crash(d)
+	reloaded, err := CreateFileDB(dir, nil)
+	if err != nil {
+		t.Fatal("CreateFileDB() error:", err)
+	}
+	if got := dump(t, reloaded); got != want {
+		t.Errorf("reloaded DB = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,234 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// changeLogSize is the number of events kept to resume change streams from
+const changeLogSize = 1000
+
+// changeEntry is a change event along with the stored document it was made
+// from, which is used to match the filters of change streams
+type changeEntry struct {
+	seq   int64
+	event *db.ChangeEvent
+	data  interface{}
+}
+
+// changeStream receives the change events published by the mutating methods of DB
+type changeStream struct {
+	d          *DB
+	collection string
+	filter     *db.Filter
+
+	mu     sync.Mutex
+	queue  []*db.ChangeEvent
+	notify chan struct{}
+	closed bool
+	event  *db.ChangeEvent
+	err    error
+}
+
+// Watch streams the changes made to documents of the collection which match
+// the filter. Inserts, updates and deletes made through DB are published to
+// every open stream, and streams can be resumed from any of the last 1000 events
+func (d *DB) Watch(ctx context.Context, collection string, filter *db.Filter, opts *db.WatchOptions) (db.ChangeStream, error) {
+	if err := d.record(Call{Method: "Watch", Collection: collection, Filter: filter}); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.Lock()
+	defer d.Unlock()
+	if err := d.checkClosed("Watch"); err != nil {
+		return nil, err
+	}
+
+	s := &changeStream{
+		d:          d,
+		collection: collection,
+		filter:     filter,
+		notify:     make(chan struct{}, 1),
+	}
+
+	if opts != nil && opts.ResumeAfter != nil {
+		seq, err := parseResumeToken(opts.ResumeAfter)
+		if err != nil {
+			return nil, fmt.Errorf("mock.DB.Watch() error: %v", err)
+		}
+		if len(d.changeLog) > 0 && seq < d.changeLog[0].seq-1 {
+			return nil, errors.New("mock.DB.Watch() error: resume token is no longer in the change log")
+		}
+		for _, entry := range d.changeLog {
+			if entry.seq > seq && s.matches(entry) {
+				s.queue = append(s.queue, entry.event)
+			}
+		}
+	}
+
+	if d.changeStreams == nil {
+		d.changeStreams = make(map[*changeStream]bool)
+	}
+	d.changeStreams[s] = true
+	return s, nil
+}
+
+// publish sends a change event to every open change stream upon the collection,
+// the caller must hold the lock of the DB
+func (d *DB) publish(collection string, opType db.OperationType, data interface{}) {
+	d.changeSeq++
+	entry := &changeEntry{seq: d.changeSeq, data: data}
+	entry.event = &db.ChangeEvent{
+		OperationType: opType,
+		Collection:    collection,
+		ResumeToken:   resumeToken(d.changeSeq),
+		Registry:      d.bsonRegistry(),
+	}
+	if doc, err := bson.MarshalWithRegistry(d.bsonRegistry(), data); err == nil {
+		if id, err := bson.Raw(doc).LookupErr("_id"); err == nil {
+			if key, err := bson.Marshal(bson.D{{Key: "_id", Value: id}}); err == nil {
+				entry.event.DocumentKey = key
+			}
+		}
+		if opType != db.OperationDelete {
+			entry.event.FullDocument = doc
+		}
+	}
+
+	d.changeLog = append(d.changeLog, entry)
+	if len(d.changeLog) > changeLogSize {
+		d.changeLog = d.changeLog[len(d.changeLog)-changeLogSize:]
+	}
+
+	for s := range d.changeStreams {
+		if s.matches(entry) {
+			s.push(entry.event)
+		}
+	}
+}
+
+// matches reports whether the change belongs to the stream. Like a MongoDB
+// change stream built by db.ConvertToWatchPipeline, deletes are only matched
+// against the _id of the filter as they hold no full document
+func (s *changeStream) matches(entry *changeEntry) bool {
+	if entry.event.Collection != s.collection {
+		return false
+	}
+	if s.filter == nil {
+		return true
+	}
+	if entry.event.OperationType == db.OperationDelete {
+		id, ok := (*s.filter)["_id"]
+		return !ok || s.d.matches(entry.data, &db.Filter{"_id": id})
+	}
+	return s.d.matches(entry.data, s.filter)
+}
+
+func (s *changeStream) push(event *db.ChangeEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.queue = append(s.queue, event)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *changeStream) Next(ctx context.Context) bool {
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			s.event = s.queue[0]
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+			return true
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return false
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.err = ctx.Err()
+			s.mu.Unlock()
+			return false
+		}
+	}
+}
+
+func (s *changeStream) Event() *db.ChangeEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.event
+}
+
+func (s *changeStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *changeStream) Close(ctx context.Context) error {
+	s.d.Lock()
+	delete(s.d.changeStreams, s)
+	s.d.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.queue = nil
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// closeStreams ends every open change stream with a *db.ClosedError,
+// the caller must hold the lock of the DB
+func (d *DB) closeStreams() {
+	for s := range d.changeStreams {
+		s.mu.Lock()
+		s.closed = true
+		s.queue = nil
+		s.err = &db.ClosedError{Database: "mock"}
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+		s.mu.Unlock()
+	}
+	d.changeStreams = nil
+}
+
+func resumeToken(seq int64) bson.Raw {
+	token, _ := bson.Marshal(bson.D{{Key: "_data", Value: strconv.FormatInt(seq, 16)}})
+	return token
+}
+
+func parseResumeToken(token bson.Raw) (int64, error) {
+	data, ok := token.Lookup("_data").StringValueOK()
+	if !ok {
+		return 0, errors.New("invalid resume token")
+	}
+	seq, err := strconv.ParseInt(data, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resume token: %v", err)
+	}
+	return seq, nil
+}
@@ -0,0 +1,234 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// nextEvent reads the next event of the stream or fails after a second
+func nextEvent(t *testing.T, s db.ChangeStream) *db.ChangeEvent {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if !s.Next(ctx) {
+		t.Fatal("ChangeStream.Next() error:", s.Err())
+	}
+	return s.Event()
+}
+
+func TestDB_Watch(t *testing.T) {
+	t.Parallel()
+	for name, d := range map[string]*DB{"go": CreateDB(), "bson": CreateBSONDB(nil)} {
+		d := d
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var database db.Database = d
+			ctx := context.Background()
+			all, err := database.Watch(ctx, "col", nil, nil)
+			if err != nil {
+				t.Fatal("DB.Watch() error:", err)
+			}
+			defer all.Close(ctx)
+			filtered, err := database.Watch(ctx, "col", &db.Filter{"name": "foo"}, nil)
+			if err != nil {
+				t.Fatal("DB.Watch() error:", err)
+			}
+			defer filtered.Close(ctx)
+
+			if err := d.Insert("other", taggedObj{ID: "0"}); err != nil {
+				t.Fatal("error inserting:", err)
+			}
+			if err := d.Insert("col", testObj{Name: "bar", Value: 1}); err != nil {
+				t.Fatal("error inserting:", err)
+			}
+			if err := d.Insert("col", testObj{Name: "foo", Value: 2}); err != nil {
+				t.Fatal("error inserting:", err)
+			}
+			if err := d.Update("col", testObj{Name: "foo", Value: 3}, &db.Filter{"name": "foo"}); err != nil {
+				t.Fatal("error updating:", err)
+			}
+			if err := d.Upsert("col", testObj{Name: "foo", Value: 4}, &db.Filter{"name": "foo"}); err != nil {
+				t.Fatal("error upserting:", err)
+			}
+			if err := d.Delete("col", &db.Filter{"name": "bar"}); err != nil {
+				t.Fatal("error deleting:", err)
+			}
+
+			want := []struct {
+				op   db.OperationType
+				name string
+			}{
+				{db.OperationInsert, "bar"},
+				{db.OperationInsert, "foo"},
+				{db.OperationReplace, "foo"},
+				{db.OperationReplace, "foo"},
+				{db.OperationDelete, ""},
+			}
+			var tokens []*db.ChangeEvent
+			for _, w := range want {
+				e := nextEvent(t, all)
+				tokens = append(tokens, e)
+				if e.OperationType != w.op || e.Collection != "col" {
+					t.Fatalf("ChangeStream.Event() = %v %v, want %v col", e.OperationType, e.Collection, w.op)
+				}
+				var obj testObj
+				err := e.Decode(&obj)
+				if (err != nil) != (w.op == db.OperationDelete) || obj.Name != w.name {
+					t.Errorf("ChangeEvent.Decode() = %v, %v, want %v", obj, err, w.name)
+				}
+			}
+			for _, w := range want[1:] {
+				if e := nextEvent(t, filtered); e.OperationType != w.op {
+					t.Errorf("filtered ChangeStream.Event() = %v, want %v", e.OperationType, w.op)
+				}
+			}
+
+			resumed, err := d.Watch(ctx, "col", nil, db.CreateWatchOptions().SetResumeAfter(tokens[1].ResumeToken))
+			if err != nil {
+				t.Fatal("DB.Watch() resume error:", err)
+			}
+			defer resumed.Close(ctx)
+			for _, w := range want[2:] {
+				if e := nextEvent(t, resumed); e.OperationType != w.op {
+					t.Errorf("resumed ChangeStream.Event() = %v, want %v", e.OperationType, w.op)
+				}
+			}
+		})
+	}
+}
+
+func TestDB_Watch_Resume_Errors(t *testing.T) {
+	d := CreateDB()
+	ctx := context.Background()
+	for i := 0; i < changeLogSize+2; i++ {
+		if err := d.Insert("col", testObj{Value: i}); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+	tests := []struct {
+		name  string
+		token []byte
+	}{
+		{"expired", resumeToken(1)},
+		{"invalid", resumeToken(0)[:0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := d.Watch(ctx, "col", nil, db.CreateWatchOptions().SetResumeAfter(tt.token)); err == nil {
+				t.Errorf("DB.Watch() expected error")
+			}
+		})
+	}
+	if _, err := d.Watch(ctx, "col", nil, db.CreateWatchOptions().SetResumeAfter(resumeToken(2))); err != nil {
+		t.Errorf("DB.Watch() error: %v", err)
+	}
+}
+
+func TestChangeStream_Close(t *testing.T) {
+	d := CreateDB()
+	s, err := d.Watch(context.Background(), "col", nil, nil)
+	if err != nil {
+		t.Fatal("DB.Watch() error:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if s.Next(ctx) || s.Err() != context.Canceled {
+		t.Errorf("ChangeStream.Next() with canceled ctx, err = %v", s.Err())
+	}
+
+	done := make(chan bool)
+	go func() { done <- s.Next(context.Background()) }()
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatal("ChangeStream.Close() error:", err)
+	}
+	select {
+	case ok := <-done:
+		if ok {
+			t.Errorf("ChangeStream.Next() = true after close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ChangeStream.Next() blocked after close")
+	}
+	if err := d.Insert("col", testObj{}); err != nil {
+		t.Fatal("error inserting:", err)
+	}
+	if len(d.changeStreams) != 0 {
+		t.Errorf("closed stream still registered")
+	}
+}
+
+func TestDB_Update_Unchanged(t *testing.T) {
+	for name, d := range map[string]*DB{"go": CreateDB(), "bson": CreateBSONDB(nil)} {
+		t.Run(name, func(t *testing.T) {
+			obj := testObj{Name: "foo", Value: 1}
+			if err := d.Insert("col", obj); err != nil {
+				t.Fatal("error inserting:", err)
+			}
+			s, err := d.Watch(context.Background(), "col", nil, nil)
+			if err != nil {
+				t.Fatal("DB.Watch() error:", err)
+			}
+			defer s.Close(context.Background())
+			if err := d.Update("col", obj, &db.Filter{"name": "foo"}); err != nil {
+				t.Fatal("DB.Update() unchanged error:", err)
+			}
+			if err := d.Upsert("col", obj, &db.Filter{"name": "foo"}); err != nil {
+				t.Fatal("DB.Upsert() unchanged error:", err)
+			}
+			if err := d.Update("col", obj, &db.Filter{"name": "bar"}); !errors.Is(err, db.ErrNotFound) {
+				t.Errorf("DB.Update() error = %v, want db.ErrNotFound", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if s.Next(ctx) {
+				t.Errorf("unchanged update published %v", s.Event())
+			}
+		})
+	}
+}
+
+func TestDB_Watch_Deletes(t *testing.T) {
+	d := CreateBSONDB(nil)
+	ctx := context.Background()
+	byID, err := d.Watch(ctx, "col", &db.Filter{"_id": "2"}, nil)
+	if err != nil {
+		t.Fatal("DB.Watch() error:", err)
+	}
+	defer byID.Close(ctx)
+	byName, err := d.Watch(ctx, "col", &db.Filter{"description": "two"}, nil)
+	if err != nil {
+		t.Fatal("DB.Watch() error:", err)
+	}
+	defer byName.Close(ctx)
+
+	for _, obj := range []taggedObj{{ID: "1", Desc: "one"}, {ID: "2", Desc: "two"}} {
+		if err := d.Insert("col", obj); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+	for _, id := range []string{"1", "2"} {
+		if err := d.Delete("col", &db.Filter{"_id": id}); err != nil {
+			t.Fatal("error deleting:", err)
+		}
+	}
+
+	// deletes are matched on their document key only when the filter has an _id
+	want := map[db.ChangeStream][]string{
+		byID:   {"insert 2", "delete 2"},
+		byName: {"insert 2", "delete 1", "delete 2"},
+	}
+	for s, events := range want {
+		for _, w := range events {
+			e := nextEvent(t, s)
+			id, _ := e.DocumentKey.Lookup("_id").StringValueOK()
+			if got := string(e.OperationType) + " " + id; got != w {
+				t.Errorf("ChangeStream.Event() = %v, want %v", got, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package mongodb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultConnectTimeout is used to connect when the config sets no timeout
+const DefaultConnectTimeout = 10 * time.Second
+
+// Config holds the settings of a MongoClient, it is read from a file with
+// LoadConfig or from environment variables with ConfigFromEnv and passed to
+// NewMongoClientFromConfig. Zero values leave the driver's defaults in place
+type Config struct {
+	URI      string `yaml:"uri"`
+	Database string `yaml:"database"`
+	AppName  string `yaml:"app_name"`
+	// Collections are resolved upfront, any other collection when first used
+	Collections []string `yaml:"collections"`
+	// SearchIndices lists the text indexed fields of each collection
+	SearchIndices map[string][]string `yaml:"search_indices"`
+
+	MinPoolSize uint64 `yaml:"min_pool_size"`
+	MaxPoolSize uint64 `yaml:"max_pool_size"`
+
+	ConnectTimeout         time.Duration `yaml:"connect_timeout"`
+	SocketTimeout          time.Duration `yaml:"socket_timeout"`
+	ServerSelectionTimeout time.Duration `yaml:"server_selection_timeout"`
+
+	// ReadConcern is one of local, available, majority, linearizable or snapshot
+	ReadConcern string `yaml:"read_concern"`
+	// WriteConcern is majority, the number of members to acknowledge or a tag set
+	WriteConcern string `yaml:"write_concern"`
+	// Journal waits for the on-disk journal when true, nil keeps the default
+	Journal *bool `yaml:"journal"`
+
+	// TLSCAFile is a PEM file of the certificate authorities to trust and
+	// TLSCertificateKeyFile a PEM file holding the client certificate and its key,
+	// TLS is enabled when either is set
+	TLSCAFile             string `yaml:"tls_ca_file"`
+	TLSCertificateKeyFile string `yaml:"tls_certificate_key_file"`
+}
+
+// LoadConfig reads the config from a YAML or JSON file, durations are written like "5s"
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("mongodb.LoadConfig() error: %v", err)
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("mongodb.LoadConfig() error parsing %v: %v", path, err)
+	}
+	return c, nil
+}
+
+// ConfigFromEnv reads the config from the environment variables starting with prefix,
+// see ApplyEnv for their names
+func ConfigFromEnv(prefix string) (*Config, error) {
+	c := &Config{}
+	if err := c.ApplyEnv(prefix); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ApplyEnv overrides the config with the environment variables which are set,
+// named after the fields with the prefix, such as STOCKPILE_MONGO_URI for the
+// prefix STOCKPILE_MONGO_:
+//
+//	URI, DATABASE, APP_NAME, COLLECTIONS (comma separated),
+//	SEARCH_INDICES (comma separated collection.field), MIN_POOL_SIZE, MAX_POOL_SIZE,
+//	CONNECT_TIMEOUT, SOCKET_TIMEOUT, SERVER_SELECTION_TIMEOUT, READ_CONCERN,
+//	WRITE_CONCERN, JOURNAL, TLS_CA_FILE, TLS_CERTIFICATE_KEY_FILE
+func (c *Config) ApplyEnv(prefix string) error {
+	return c.applyEnv(prefix, os.LookupEnv)
+}
+
+func (c *Config) applyEnv(prefix string, lookup func(string) (string, bool)) error {
+	var errs []string
+	env := func(name string, set func(string) error) {
+		value, ok := lookup(prefix + name)
+		if !ok {
+			return
+		}
+		if err := set(value); err != nil {
+			errs = append(errs, fmt.Sprintf("%v%v: %v", prefix, name, err))
+		}
+	}
+	str := func(field *string) func(string) error {
+		return func(value string) error {
+			*field = value
+			return nil
+		}
+	}
+	size := func(field *uint64) func(string) error {
+		return func(value string) (err error) {
+			*field, err = strconv.ParseUint(value, 10, 64)
+			return err
+		}
+	}
+	duration := func(field *time.Duration) func(string) error {
+		return func(value string) (err error) {
+			*field, err = time.ParseDuration(value)
+			return err
+		}
+	}
+
+	env("URI", str(&c.URI))
+	env("DATABASE", str(&c.Database))
+	env("APP_NAME", str(&c.AppName))
+	env("COLLECTIONS", func(value string) error {
+		c.Collections = splitList(value)
+		return nil
+	})
+	env("SEARCH_INDICES", func(value string) error {
+		c.SearchIndices = make(map[string][]string)
+		for _, index := range splitList(value) {
+			dot := strings.Index(index, ".")
+			if dot <= 0 || dot == len(index)-1 {
+				return fmt.Errorf("%q is not collection.field", index)
+			}
+			c.SearchIndices[index[:dot]] = append(c.SearchIndices[index[:dot]], index[dot+1:])
+		}
+		return nil
+	})
+	env("MIN_POOL_SIZE", size(&c.MinPoolSize))
+	env("MAX_POOL_SIZE", size(&c.MaxPoolSize))
+	env("CONNECT_TIMEOUT", duration(&c.ConnectTimeout))
+	env("SOCKET_TIMEOUT", duration(&c.SocketTimeout))
+	env("SERVER_SELECTION_TIMEOUT", duration(&c.ServerSelectionTimeout))
+	env("READ_CONCERN", str(&c.ReadConcern))
+	env("WRITE_CONCERN", str(&c.WriteConcern))
+	env("JOURNAL", func(value string) error {
+		journal, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		c.Journal = &journal
+		return nil
+	})
+	env("TLS_CA_FILE", str(&c.TLSCAFile))
+	env("TLS_CERTIFICATE_KEY_FILE", str(&c.TLSCertificateKeyFile))
+
+	if len(errs) > 0 {
+		return errors.New("mongodb.Config.ApplyEnv() error: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate checks the config without connecting, reporting every invalid setting
+func (c *Config) Validate() error {
+	var errs []string
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if c.URI == "" {
+		invalid("uri is required")
+	} else if u, err := url.Parse(c.URI); err != nil {
+		invalid("uri is invalid: %v", err)
+	} else if u.Scheme != "mongodb" && u.Scheme != "mongodb+srv" {
+		invalid("uri scheme must be mongodb or mongodb+srv, got %q", u.Scheme)
+	}
+	if c.Database == "" {
+		invalid("database is required")
+	}
+	for _, collection := range sortedKeys(c.SearchIndices) {
+		if collection == "" || len(c.SearchIndices[collection]) == 0 {
+			invalid("search index of collection %q has no fields", collection)
+		}
+	}
+	if c.MaxPoolSize > 0 && c.MinPoolSize > c.MaxPoolSize {
+		invalid("min pool size %d is above max pool size %d", c.MinPoolSize, c.MaxPoolSize)
+	}
+	for _, timeout := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"connect timeout", c.ConnectTimeout},
+		{"socket timeout", c.SocketTimeout},
+		{"server selection timeout", c.ServerSelectionTimeout},
+	} {
+		if timeout.d < 0 {
+			invalid("%v must not be negative, got %v", timeout.name, timeout.d)
+		}
+	}
+	switch c.ReadConcern {
+	case "", "local", "available", "majority", "linearizable", "snapshot":
+	default:
+		invalid("read concern %q is not local, available, majority, linearizable or snapshot", c.ReadConcern)
+	}
+	if err := c.writeOptions().Validate(); err != nil {
+		invalid("%v", err)
+	}
+	for _, path := range []string{c.TLSCAFile, c.TLSCertificateKeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			invalid("tls file: %v", err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New("mongodb.Config is invalid: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ClientOptions validates the config and converts it into the options of the mongo driver
+func (c *Config) ClientOptions() (*options.ClientOptions, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	opts := options.Client().ApplyURI(c.URI)
+	if c.AppName != "" {
+		opts.SetAppName(c.AppName)
+	}
+	if c.MinPoolSize > 0 {
+		opts.SetMinPoolSize(c.MinPoolSize)
+	}
+	if c.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(c.MaxPoolSize)
+	}
+	if c.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(c.ConnectTimeout)
+	}
+	if c.SocketTimeout > 0 {
+		opts.SetSocketTimeout(c.SocketTimeout)
+	}
+	if c.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(c.ServerSelectionTimeout)
+	}
+	if c.ReadConcern != "" {
+		opts.SetReadConcern(readconcern.New(readconcern.Level(c.ReadConcern)))
+	}
+	if wc := c.writeConcern(); wc != nil {
+		opts.SetWriteConcern(wc)
+	}
+	if c.TLSCAFile != "" || c.TLSCertificateKeyFile != "" {
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	return opts, opts.Validate()
+}
+
+// writeOptions returns the write concern and journal of the config as db.WriteOptions
+func (c *Config) writeOptions() *db.WriteOptions {
+	o := db.CreateWriteOptions().SetWriteConcern(c.WriteConcern)
+	if c.Journal != nil {
+		o.SetJournal(*c.Journal)
+	}
+	return o
+}
+
+// writeConcern returns the write concern of the config, nil to keep the default
+func (c *Config) writeConcern() *writeconcern.WriteConcern {
+	o, err := db.ConvertToWriteCollectionOptions(c.writeOptions())
+	if err != nil || o == nil {
+		return nil
+	}
+	return o.WriteConcern
+}
+
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if c.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("mongodb.Config error reading tls ca file: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("mongodb.Config tls ca file %v holds no certificate", c.TLSCAFile)
+		}
+	}
+	if c.TLSCertificateKeyFile != "" {
+		pem, err := ioutil.ReadFile(c.TLSCertificateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("mongodb.Config error reading tls certificate key file: %v", err)
+		}
+		cert, err := tls.X509KeyPair(pem, pem)
+		if err != nil {
+			return nil, fmt.Errorf("mongodb.Config error loading tls certificate key file: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// searchIndices converts the search indices into the form taken by NewMongoClient
+func (c *Config) searchIndices() map[string]map[string]bool {
+	indices := make(map[string]map[string]bool, len(c.SearchIndices))
+	for collection, fields := range c.SearchIndices {
+		indices[collection] = make(map[string]bool, len(fields))
+		for _, field := range fields {
+			indices[collection][field] = true
+		}
+	}
+	return indices
+}
+
+// NewMongoClientFromConfig validates the config and creates a client with it, Open connects it
+func NewMongoClientFromConfig(c *Config) (*MongoClient, error) {
+	opts, err := c.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	return NewMongoClient(c.Database, c.Collections, opts, c.searchIndices())
+}
@@ -0,0 +1,247 @@
+package mongodb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	journal := true
+	tests := []struct {
+		name    string
+		path    string
+		want    *Config
+		wantErr bool
+	}{
+		{"yaml", "testdata/config.yaml", &Config{
+			URI:                    "mongodb://localhost:27017/?replicaSet=rs0",
+			Database:               "app",
+			AppName:                "billing",
+			Collections:            []string{"users", "payments"},
+			SearchIndices:          map[string][]string{"posts": {"title", "body"}},
+			MinPoolSize:            5,
+			MaxPoolSize:            50,
+			ConnectTimeout:         5 * time.Second,
+			SocketTimeout:          30 * time.Second,
+			ServerSelectionTimeout: 2 * time.Second,
+			ReadConcern:            "majority",
+			WriteConcern:           "majority",
+			Journal:                &journal,
+		}, false},
+		{"json", "testdata/config.json", &Config{
+			URI:          "mongodb+srv://cluster.example.com",
+			Database:     "app",
+			MaxPoolSize:  10,
+			WriteConcern: "2",
+		}, false},
+		{"missing", "testdata/missing.yaml", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadConfig(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "unknown.yaml")
+	if err := ioutil.WriteFile(path, []byte("uri: mongodb://localhost\ndatabse: app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Errorf("LoadConfig() expected error for a misspelled field")
+	}
+}
+
+func TestConfig_applyEnv(t *testing.T) {
+	env := map[string]string{
+		"APP_MONGO_URI":                      "mongodb://localhost",
+		"APP_MONGO_DATABASE":                 "app",
+		"APP_MONGO_COLLECTIONS":              "users, payments",
+		"APP_MONGO_SEARCH_INDICES":           "posts.title,posts.body",
+		"APP_MONGO_MAX_POOL_SIZE":            "20",
+		"APP_MONGO_SERVER_SELECTION_TIMEOUT": "1500ms",
+		"APP_MONGO_JOURNAL":                  "true",
+		// other prefixes are ignored
+		"OTHER_MONGO_DATABASE": "other",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	// the environment overrides the fields it sets
+	journal := true
+	c := &Config{Database: "file", AppName: "billing"}
+	if err := c.applyEnv("APP_MONGO_", lookup); err != nil {
+		t.Fatal("Config.applyEnv() error:", err)
+	}
+	want := &Config{
+		URI:                    "mongodb://localhost",
+		Database:               "app",
+		AppName:                "billing",
+		Collections:            []string{"users", "payments"},
+		SearchIndices:          map[string][]string{"posts": {"title", "body"}},
+		MaxPoolSize:            20,
+		ServerSelectionTimeout: 1500 * time.Millisecond,
+		Journal:                &journal,
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Config.applyEnv() = %+v, want %+v", c, want)
+	}
+
+	env["APP_MONGO_MAX_POOL_SIZE"] = "-1"
+	env["APP_MONGO_CONNECT_TIMEOUT"] = "10"
+	err := (&Config{}).applyEnv("APP_MONGO_", lookup)
+	if err == nil || !strings.Contains(err.Error(), "APP_MONGO_MAX_POOL_SIZE") ||
+		!strings.Contains(err.Error(), "APP_MONGO_CONNECT_TIMEOUT") {
+		t.Errorf("Config.applyEnv() error = %v, want both invalid variables", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	journal, noJournal := true, false
+	valid := func() Config { return Config{URI: "mongodb://localhost", Database: "app"} }
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"missing_uri", func(c *Config) { c.URI = "" }, "uri is required"},
+		{"wrong_scheme", func(c *Config) { c.URI = "postgres://localhost" }, "uri scheme"},
+		{"missing_database", func(c *Config) { c.Database = "" }, "database is required"},
+		{"empty_search_index", func(c *Config) { c.SearchIndices = map[string][]string{"posts": nil} }, "search index"},
+		{"pool_sizes", func(c *Config) { c.MinPoolSize, c.MaxPoolSize = 10, 5 }, "min pool size"},
+		{"unbounded_pool", func(c *Config) { c.MinPoolSize = 10 }, ""},
+		{"negative_timeout", func(c *Config) { c.SocketTimeout = -time.Second }, "socket timeout"},
+		{"read_concern", func(c *Config) { c.ReadConcern = "strong" }, "read concern"},
+		{"write_concern_tag", func(c *Config) { c.WriteConcern = "dc" }, ""},
+		{"negative_write_concern", func(c *Config) { c.WriteConcern = "-1" }, "write concern"},
+		{"unacknowledged_journal", func(c *Config) { c.WriteConcern, c.Journal = "0", &journal }, "journal"},
+		{"unacknowledged_no_journal", func(c *Config) { c.WriteConcern, c.Journal = "0", &noJournal }, ""},
+		{"missing_tls_file", func(c *Config) { c.TLSCAFile = "testdata/missing.pem" }, "tls file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(&c)
+			err := c.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Config.Validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Config.Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_ClientOptions(t *testing.T) {
+	c, err := LoadConfig("testdata/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := c.ClientOptions()
+	if err != nil {
+		t.Fatal("Config.ClientOptions() error:", err)
+	}
+	if *opts.AppName != "billing" || *opts.MinPoolSize != 5 || *opts.MaxPoolSize != 50 ||
+		*opts.ConnectTimeout != 5*time.Second || *opts.SocketTimeout != 30*time.Second ||
+		*opts.ServerSelectionTimeout != 2*time.Second || opts.ReplicaSet == nil || *opts.ReplicaSet != "rs0" {
+		t.Errorf("Config.ClientOptions() = %+v", opts)
+	}
+	if opts.ReadConcern.GetLevel() != "majority" {
+		t.Errorf("Config.ClientOptions() read concern = %v, want majority", opts.ReadConcern.GetLevel())
+	}
+	if opts.WriteConcern.GetW() != "majority" || !opts.WriteConcern.GetJ() {
+		t.Errorf("Config.ClientOptions() write concern = %v, %v", opts.WriteConcern.GetW(), opts.WriteConcern.GetJ())
+	}
+
+	for value, want := range map[string]interface{}{"2": 2, "dc": "dc"} {
+		c := &Config{WriteConcern: value}
+		if wc := c.writeConcern(); wc == nil || wc.GetW() != want {
+			t.Errorf("Config.writeConcern() w = %v, want %v", wc.GetW(), want)
+		}
+	}
+	// an explicit false journal is kept rather than left to the default
+	noJournal := false
+	if wc := (&Config{Journal: &noJournal}).writeConcern(); wc == nil || wc.GetJ() {
+		t.Errorf("Config.writeConcern() = %v, want j false", wc)
+	}
+	if wc := (&Config{}).writeConcern(); wc != nil {
+		t.Errorf("Config.writeConcern() = %v, want nil to keep the default", wc)
+	}
+
+	if _, err := (&Config{}).ClientOptions(); err == nil {
+		t.Errorf("Config.ClientOptions() expected validation error")
+	}
+}
+
+func TestConfig_tlsConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "client.pem")
+	cert, key := selfSignedCert(t)
+	if err := ioutil.WriteFile(certFile, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, append(cert, key...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{URI: "mongodb://localhost", Database: "app", TLSCAFile: certFile, TLSCertificateKeyFile: keyFile}
+	opts, err := c.ClientOptions()
+	if err != nil {
+		t.Fatal("Config.ClientOptions() error:", err)
+	}
+	if opts.TLSConfig == nil || opts.TLSConfig.RootCAs == nil || len(opts.TLSConfig.Certificates) != 1 {
+		t.Errorf("Config.ClientOptions() tls config = %+v", opts.TLSConfig)
+	}
+
+	// the certificate key file must hold the key
+	c.TLSCertificateKeyFile = certFile
+	if _, err := c.ClientOptions(); err == nil {
+		t.Errorf("Config.ClientOptions() expected error for a certificate without key")
+	}
+}
+
+// selfSignedCert returns a PEM encoded self signed certificate and its key
+func selfSignedCert(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "stockpile"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
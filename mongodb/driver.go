@@ -0,0 +1,75 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	db.Register("mongodb", connect)
+	db.Register("mongodb+srv", connect)
+}
+
+// connectConfig is the part of a db.Connect URL read by stockpile
+type connectConfig struct {
+	uri           string
+	dbName        string
+	collections   []string
+	searchIndices map[string]map[string]bool
+}
+
+// parseConnectURL reads the database name from the db parameter, or the path
+// when missing, the comma separated collections from the collections parameter
+// and the text indexed fields as collection.field from the search parameter.
+// These parameters are removed from the URI passed to the mongo driver
+func parseConnectURL(u *url.URL) (*connectConfig, error) {
+	query := u.Query()
+	c := &connectConfig{dbName: query.Get("db"), searchIndices: make(map[string]map[string]bool)}
+	if c.dbName == "" {
+		c.dbName = strings.TrimPrefix(u.Path, "/")
+	}
+	if c.dbName == "" {
+		return nil, errors.New("mongodb url is missing the db parameter")
+	}
+	for _, collection := range strings.Split(query.Get("collections"), ",") {
+		if collection != "" {
+			c.collections = append(c.collections, collection)
+		}
+	}
+	for _, index := range strings.Split(query.Get("search"), ",") {
+		if index == "" {
+			continue
+		}
+		dot := strings.Index(index, ".")
+		if dot <= 0 || dot == len(index)-1 {
+			return nil, errors.New("mongodb url search parameter must be collection.field: " + index)
+		}
+		collection, field := index[:dot], index[dot+1:]
+		if c.searchIndices[collection] == nil {
+			c.searchIndices[collection] = make(map[string]bool)
+		}
+		c.searchIndices[collection][field] = true
+	}
+
+	query.Del("db")
+	query.Del("collections")
+	query.Del("search")
+	stripped := *u
+	stripped.RawQuery = query.Encode()
+	c.uri = stripped.String()
+	return c, nil
+}
+
+// connect creates a MongoClient for db.Connect from a "mongodb://" URL
+func connect(ctx context.Context, u *url.URL) (db.Database, error) {
+	c, err := parseConnectURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return NewMongoClient(c.dbName, c.collections, options.Client().ApplyURI(c.uri), c.searchIndices)
+}
@@ -0,0 +1,60 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Ping checks that the primary, or the member selected by the read
+// preference of the client, can be reached
+func (c *MongoClient) Ping(ctx context.Context) error {
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if err := c.client().Ping(ctx, nil); err != nil {
+		return fmt.Errorf("error mongo ping: %w", err)
+	}
+	return nil
+}
+
+// isMasterResult is the part of the isMaster command response describing the topology
+type isMasterResult struct {
+	SetName string   `bson:"setName"`
+	Hosts   []string `bson:"hosts"`
+	Msg     string   `bson:"msg"`
+	Me      string   `bson:"me"`
+}
+
+// Health pings the database and describes its topology
+// as "standalone", "replica set <name>" or "sharded"
+func (c *MongoClient) Health(ctx context.Context) db.Health {
+	h := db.CheckHealth(ctx, c.Ping)
+	if !h.Healthy {
+		return h
+	}
+	var res isMasterResult
+	err := c.client().Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&res)
+	if err != nil {
+		h.Healthy = false
+		h.Err = fmt.Errorf("error mongo health: %w", err)
+		return h
+	}
+	h.Topology, h.Servers = describeTopology(res)
+	return h
+}
+
+func describeTopology(res isMasterResult) (string, []string) {
+	switch {
+	case res.Msg == "isdbgrid":
+		return "sharded", nil
+	case res.SetName != "":
+		return "replica set " + res.SetName, res.Hosts
+	}
+	if res.Me != "" {
+		return "standalone", []string{res.Me}
+	}
+	return "standalone", nil
+}
@@ -4,43 +4,88 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
+	"sync"
 
 	"github.com/sschwartz96/stockpile/db"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// MongoClient holds the connection to the database
+// MongoClient holds the connection to the database, it is connected by Open
+// and can be opened again after Close
 type MongoClient struct {
+	// Client is replaced by a new client when opened again after Close
 	*mongo.Client
-	collectionMap map[string]*mongo.Collection
+	database      *mongo.Database
 	searchIndices map[string](map[string]bool)
+	registry      *bsoncodec.Registry
+
+	dbName      string
+	collections []string
+	opts        *options.ClientOptions
+	// stateMu guards the connection state, open is set by Open until Close
+	// and connected once Client was connected, so it can not be reused
+	stateMu   sync.RWMutex
+	open      bool
+	connected bool
+
+	// collectionMap caches the collections resolved by collection
+	collectionMu  sync.RWMutex
+	collectionMap map[string]*mongo.Collection
+	// allowed restricts the collections which can be used, any when nil
+	allowed map[string]bool
 }
 
-// NewMongoClient makes a connection with the mongo client
+// NewMongoClient creates a client without connecting, Open connects to the
+// database. The collections are resolved upfront while any other collection
+// is resolved when first used
 func NewMongoClient(dbName string, collections []string, opts *options.ClientOptions, searchIndices map[string](map[string]bool)) (*MongoClient, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// connect to client
-	client, err := mongo.Connect(ctx, opts)
+	if opts == nil {
+		opts = options.Client()
+	}
+	client, err := mongo.NewClient(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	// confirm the connection with a ping
-	err = client.Ping(ctx, nil)
-	if err != nil {
-		return nil, err
+	registry := bson.DefaultRegistry
+	if opts.Registry != nil {
+		registry = opts.Registry
 	}
 
-	return &MongoClient{
-		Client:        client,
-		collectionMap: createCollectionMap(client.Database(dbName), collections),
+	c := &MongoClient{
+		dbName:        dbName,
+		collections:   collections,
+		opts:          opts,
 		searchIndices: searchIndices,
-	}, nil
+		registry:      registry,
+	}
+	c.setClient(client)
+	return c, nil
+}
+
+// setClient uses the client and resolves the collections from it
+func (c *MongoClient) setClient(client *mongo.Client) {
+	c.collectionMu.Lock()
+	defer c.collectionMu.Unlock()
+	c.Client = client
+	c.database = client.Database(c.dbName)
+	c.collectionMap = createCollectionMap(c.database, c.collections)
+}
+
+// client returns the mongo client, which is replaced when the client is opened again
+func (c *MongoClient) client() *mongo.Client {
+	c.collectionMu.RLock()
+	defer c.collectionMu.RUnlock()
+	return c.Client
+}
+
+// Registry returns the bson registry used to marshal and unmarshal documents,
+// it can be passed to mock.CreateBSONDB so the mock decodes the same way
+func (c *MongoClient) Registry() *bsoncodec.Registry {
+	return c.registry
 }
 
 // createCollectionMap creates a map of mongo collections so the program doesn't
@@ -53,18 +98,142 @@ func createCollectionMap(db *mongo.Database, collections []string) map[string]*m
 	return collectionMap
 }
 
-func (m *MongoClient) Open(ctx context.Context) error {
-	// already opened when using CreateMongoClient
+// SetAllowedCollections restricts the client to the collections, using any
+// other collection returns a *db.UnknownCollectionError
+func (c *MongoClient) SetAllowedCollections(collections ...string) *MongoClient {
+	c.collectionMu.Lock()
+	defer c.collectionMu.Unlock()
+	c.allowed = make(map[string]bool, len(collections))
+	for _, collection := range collections {
+		c.allowed[collection] = true
+	}
+	return c
+}
+
+// collection returns the cached collection, resolving it on first use,
+// or a *db.ClosedError when the client is not open
+func (c *MongoClient) collection(name string) (*mongo.Collection, error) {
+	if err := c.checkOpen(); err != nil {
+		return nil, err
+	}
+	c.collectionMu.RLock()
+	col, ok := c.collectionMap[name]
+	allowed := c.allowed == nil || c.allowed[name]
+	c.collectionMu.RUnlock()
+	if name == "" || !allowed {
+		return nil, &db.UnknownCollectionError{Collection: name}
+	}
+	if ok {
+		return col, nil
+	}
+
+	c.collectionMu.Lock()
+	defer c.collectionMu.Unlock()
+	if col, ok := c.collectionMap[name]; ok {
+		return col, nil
+	}
+	col = c.database.Collection(name)
+	c.collectionMap[name] = col
+	return col, nil
+}
+
+// readCollection returns the collection with the read preference and
+// read concern of opts applied
+func (c *MongoClient) readCollection(name string, opts *db.Options) (*mongo.Collection, error) {
+	col, err := c.collection(name)
+	if err != nil {
+		return nil, err
+	}
+	o, err := db.ConvertToReadCollectionOptions(opts)
+	if err != nil || o == nil {
+		return col, err
+	}
+	return col.Clone(o)
+}
+
+// writeCollection returns the collection with the write concern of opts applied
+func (c *MongoClient) writeCollection(name string, opts []*db.WriteOptions) (*mongo.Collection, error) {
+	col, err := c.collection(name)
+	if err != nil {
+		return nil, err
+	}
+	o, err := db.ConvertToWriteCollectionOptions(db.MergeWriteOptions(opts...))
+	if err != nil || o == nil {
+		return col, err
+	}
+	return col.Clone(o)
+}
+
+// Open connects to the database and pings it, connecting times out after the
+// connect timeout of the client options, DefaultConnectTimeout when unset,
+// unless ctx has a deadline. Opening an open client does nothing
+func (c *MongoClient) Open(ctx context.Context) error {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.open {
+		return nil
+	}
+	// a disconnected client can not connect again so a new one is created
+	if c.connected {
+		client, err := mongo.NewClient(c.opts)
+		if err != nil {
+			return err
+		}
+		c.setClient(client)
+		c.connected = false
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := DefaultConnectTimeout
+		if c.opts.ConnectTimeout != nil {
+			timeout = *c.opts.ConnectTimeout
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if err := c.Client.Connect(ctx); err != nil {
+		return err
+	}
+	c.connected = true
+	// confirm the connection with a ping
+	if err := c.Client.Ping(ctx, nil); err != nil {
+		_ = c.Client.Disconnect(ctx)
+		return err
+	}
+	c.open = true
 	return nil
 }
 
-func (m *MongoClient) Close(ctx context.Context) error {
-	return m.Disconnect(ctx)
+// Close disconnects from the database, every other method returns a
+// *db.ClosedError until the client is opened again. Closing a closed client does nothing
+func (c *MongoClient) Close(ctx context.Context) error {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if !c.open {
+		return nil
+	}
+	c.open = false
+	return c.Disconnect(ctx)
+}
+
+// checkOpen returns a *db.ClosedError unless the client is open
+func (c *MongoClient) checkOpen() error {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	if !c.open {
+		return &db.ClosedError{Database: c.dbName}
+	}
+	return nil
 }
 
 // Insert takes a collection name and interface object and inserts into collection
-func (c *MongoClient) Insert(collection string, object interface{}) error {
-	col := c.collectionMap[collection]
+func (c *MongoClient) Insert(collection string, object interface{}, opts ...*db.WriteOptions) error {
+	col, err := c.writeCollection(collection, opts)
+	if err != nil {
+		return err
+	}
 
 	res, err := col.InsertOne(context.Background(), object)
 	if err != nil {
@@ -78,7 +247,10 @@ func (c *MongoClient) Insert(collection string, object interface{}) error {
 }
 
 func (m *MongoClient) FindOne(collection string, object interface{}, filter *db.Filter, opts *db.Options) error {
-	col := m.collectionMap[collection]
+	col, err := m.readCollection(collection, opts)
+	if err != nil {
+		return err
+	}
 	f := db.ConvertToMongoFilter(filter)
 	o := db.ConvertToFindOneOptions(opts)
 	res := col.FindOne(context.Background(), f, o)
@@ -87,7 +259,10 @@ func (m *MongoClient) FindOne(collection string, object interface{}, filter *db.
 
 // FindAll finds all within the collection, using filter and options if applicable
 func (m *MongoClient) FindAll(collection string, object interface{}, filter *db.Filter, opts *db.Options) error {
-	col := m.collectionMap[collection]
+	col, err := m.readCollection(collection, opts)
+	if err != nil {
+		return err
+	}
 	f := db.ConvertToMongoFilter(filter)
 	o := db.ConvertToFindOptions(opts)
 	cur, err := col.Find(context.Background(), f, o)
@@ -98,51 +273,55 @@ func (m *MongoClient) FindAll(collection string, object interface{}, filter *db.
 	return err
 }
 
-func (m *MongoClient) Update(collection string, object interface{}, filter *db.Filter) error {
-	col := m.collectionMap[collection]
+// Update replaces the first document matching filter with object,
+// following the contract of db.Database.Update
+func (m *MongoClient) Update(collection string, object interface{}, filter *db.Filter, opts ...*db.WriteOptions) error {
+	col, err := m.writeCollection(collection, opts)
+	if err != nil {
+		return err
+	}
 	f := db.ConvertToMongoFilter(filter)
-	u := bson.M{"$set": object}
-	res, err := col.UpdateOne(context.Background(), f, u)
+	res, err := col.ReplaceOne(context.Background(), f, object)
 	if err != nil {
 		return err
 	}
-	if res.MatchedCount > 1 {
-		if res.ModifiedCount == 0 {
-			return fmt.Errorf("error mongo update: matched %v, but didn't modify", res.MatchedCount)
-		}
-	} else {
-		return fmt.Errorf("error mongo update: did not match any documents")
+	return checkUpdateResult(res)
+}
+
+// checkUpdateResult returns db.ErrNotFound when the update matched no document,
+// a matched document which was left unchanged is not an error
+func checkUpdateResult(res *mongo.UpdateResult) error {
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("error mongo update: %w", db.ErrNotFound)
 	}
 	return nil
 }
 
-// Upsert updates or inserts object within collection with premade filter
-func (c *MongoClient) Upsert(collection string, object interface{}, filter *db.Filter) error {
-	col := c.collectionMap[collection]
-	update := bson.M{"$set": object}
-	f := db.ConvertToMongoFilter(filter)
-
-	upsert := true
-	opts := &options.UpdateOptions{Upsert: &upsert}
-
-	_, err := col.UpdateOne(context.Background(), f, update, opts)
+// Upsert replaces the first document matching filter with object,
+// inserting object when no document matches
+func (c *MongoClient) Upsert(collection string, object interface{}, filter *db.Filter, opts ...*db.WriteOptions) error {
+	col, err := c.writeCollection(collection, opts)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	f := db.ConvertToMongoFilter(filter)
+	_, err = col.ReplaceOne(context.Background(), f, object, options.Replace().SetUpsert(true))
+	return err
 }
 
 // Delete deletes the certain document based on param and value
-func (c *MongoClient) Delete(collection string, filter *db.Filter) error {
-	col := c.collectionMap[collection]
+func (c *MongoClient) Delete(collection string, filter *db.Filter, opts ...*db.WriteOptions) error {
+	col, err := c.writeCollection(collection, opts)
+	if err != nil {
+		return err
+	}
 	f := db.ConvertToMongoFilter(filter)
 	res, err := col.DeleteOne(context.Background(), f)
 	if err != nil {
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("error mongo delete: deleted count == 0")
+		return fmt.Errorf("error mongo delete: %w", db.ErrNotFound)
 	}
 	return nil
 }
@@ -159,10 +338,11 @@ func (c *MongoClient) Find(collection, param string, value interface{}, object i
 
 // FindWithBSON takes in object and already made bson filter
 func (c *MongoClient) FindWithBSON(collection string, filter interface{}, opts *options.FindOneOptions, object interface{}) error {
-	var err error
-
 	// get collection
-	col := c.collectionMap[collection]
+	col, err := c.collection(collection)
+	if err != nil {
+		return err
+	}
 
 	// find operation
 	if opts == nil {
@@ -183,7 +363,10 @@ func (c *MongoClient) FindWithBSON(collection string, filter interface{}, opts *
 // and decodes into pointer to the slice
 func (c *MongoClient) FindAllWithBSON(collection string, filter interface{}, opts *options.FindOptions, slice interface{}) error {
 	// get collection
-	col := c.collectionMap[collection]
+	col, err := c.collection(collection)
+	if err != nil {
+		return err
+	}
 
 	// find operation
 	cur, err := col.Find(context.Background(), filter, opts)
@@ -196,25 +379,26 @@ func (c *MongoClient) FindAllWithBSON(collection string, filter interface{}, opt
 
 }
 
-// UpdateWithBSON takes in collection string & bson filter and update object
+// UpdateWithBSON takes in collection string & bson filter and update object,
+// following the contract of db.Database.Update
 func (c *MongoClient) UpdateWithBSON(collection string, filter, update interface{}) error {
-	col := c.collectionMap[collection]
-	r, err := col.UpdateOne(context.Background(), filter, update)
+	col, err := c.collection(collection)
 	if err != nil {
 		return err
 	}
-	if r.ModifiedCount != 1 {
-		if r.MatchedCount == 1 {
-			return errors.New("matched but not updated")
-		}
-		return errors.New("object failed to update")
+	res, err := col.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkUpdateResult(res)
 }
 
 // Exists checks if the document exists within the collection based on the filter
 func (c *MongoClient) Exists(collection string, filter interface{}) (bool, error) {
-	col := c.collectionMap[collection]
+	col, err := c.collection(collection)
+	if err != nil {
+		return false, err
+	}
 
 	// setup limit in FindOptions
 	limit := int64(1)
@@ -260,22 +444,25 @@ func (c *MongoClient) doesIndexExists(collection string, fields []string) bool {
 // Search takes a collection, search string, and slice of fields to search upon.
 // The results are unmarshalled into slice interface
 func (c *MongoClient) Search(collection, search string, fields []string, slice interface{}) error {
-	col := c.collectionMap[collection]
+	return c.SearchWithOptions(collection, search, fields, slice, nil, nil)
+}
+
+// SearchWithOptions runs a $text search combined with the filter, the results are
+// sorted by relevance unless opts sets a sort and the relevance is written to
+// opts.ScoreField when it is set
+func (c *MongoClient) SearchWithOptions(collection, search string, fields []string, slice interface{}, filter *db.Filter, opts *db.Options) error {
+	col, err := c.readCollection(collection, opts)
+	if err != nil {
+		return err
+	}
 	if !c.doesIndexExists(collection, fields) {
 		// TODO: create indices??? no, because we should have them already created
 		return errors.New("Search() search indices do not exist")
 	}
-	// create search filter
-	filter := bson.M{
-		"$text": bson.M{
-			"$search": search,
-		},
-		"score": bson.M{"$meta": "textScore"},
-	}
-	// sort by score
-	opts := options.Find().SetSort(bson.M{"score": bson.M{"$meta": "textScore"}})
+	f := db.ConvertToSearchFilter(search, filter)
+	o := db.ConvertToSearchOptions(opts)
 	// run search
-	cur, err := col.Find(context.Background(), filter, opts)
+	cur, err := col.Find(context.Background(), f, o)
 	if err != nil {
 		return err
 	}
@@ -285,7 +472,10 @@ func (c *MongoClient) Search(collection, search string, fields []string, slice i
 // Aggregate takes in a collection string, filter, pipeline, and pointer to object
 // returns error if anything is malformed
 func (c *MongoClient) Aggregate(collection string, pipeline mongo.Pipeline, object interface{}) error {
-	col := c.collectionMap[collection]
+	col, err := c.collection(collection)
+	if err != nil {
+		return err
+	}
 	cur, err := col.Aggregate(context.Background(), pipeline)
 	if err != nil {
 		return err
@@ -0,0 +1,209 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// offlineClient creates a MongoClient which is marked open without connecting
+func offlineClient(t *testing.T, collections ...string) *MongoClient {
+	c, err := NewMongoClient("test", collections, options.Client().ApplyURI("mongodb://localhost:27017"), nil)
+	if err != nil {
+		t.Fatal("NewMongoClient() error:", err)
+	}
+	c.open = true
+	return c
+}
+
+func TestMongoClient_collection(t *testing.T) {
+	c := offlineClient(t, "users")
+	users, err := c.collection("users")
+	if err != nil || users.Name() != "users" {
+		t.Fatalf("MongoClient.collection() = %v, %v", users, err)
+	}
+
+	// collections are resolved lazily once, even when requested concurrently
+	var wg sync.WaitGroup
+	resolved := make([]*mongo.Collection, 10)
+	for i := range resolved {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resolved[i], _ = c.collection("posts")
+		}(i)
+	}
+	wg.Wait()
+	for _, col := range resolved {
+		if col == nil || col != resolved[0] || col.Name() != "posts" {
+			t.Fatalf("MongoClient.collection() resolved %v, want the same posts collection", resolved)
+		}
+	}
+
+	if _, err := c.collection(""); !errors.Is(err, db.ErrUnknownCollection) {
+		t.Errorf("MongoClient.collection(\"\") error = %v, want unknown collection", err)
+	}
+
+	c.SetAllowedCollections("users")
+	if _, err := c.collection("users"); err != nil {
+		t.Errorf("MongoClient.collection() allowed error = %v", err)
+	}
+	if _, err := c.collection("posts"); !errors.Is(err, db.ErrUnknownCollection) {
+		t.Errorf("MongoClient.collection() not allowed error = %v, want unknown collection", err)
+	}
+}
+
+func TestMongoClient_UnknownCollection(t *testing.T) {
+	c := offlineClient(t).SetAllowedCollections("users")
+	var obj struct{}
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"Insert", func() error { return c.Insert("typo", obj) }},
+		{"FindOne", func() error { return c.FindOne("typo", &obj, nil, nil) }},
+		{"FindAll", func() error { return c.FindAll("typo", &[]struct{}{}, nil, nil) }},
+		{"Update", func() error { return c.Update("typo", obj, &db.Filter{"a": 1}) }},
+		{"Upsert", func() error { return c.Upsert("typo", obj, &db.Filter{"a": 1}) }},
+		{"Delete", func() error { return c.Delete("typo", &db.Filter{"a": 1}) }},
+		{"Search", func() error { return c.Search("typo", "a", []string{"a"}, &[]struct{}{}) }},
+		{"Exists", func() error { _, err := c.Exists("typo", nil); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unknown *db.UnknownCollectionError
+			err := tt.call()
+			if !errors.As(err, &unknown) || unknown.Collection != "typo" {
+				t.Errorf("MongoClient.%v() error = %v, want unknown collection", tt.name, err)
+			}
+			if fmt.Sprint(err) != `unknown collection "typo"` {
+				t.Errorf("MongoClient.%v() error = %v", tt.name, err)
+			}
+		})
+	}
+}
+
+func Test_checkUpdateResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		res     *mongo.UpdateResult
+		wantErr error
+	}{
+		{"modified", &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil},
+		{"unchanged", &mongo.UpdateResult{MatchedCount: 1}, nil},
+		{"not_found", &mongo.UpdateResult{}, db.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkUpdateResult(tt.res); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkUpdateResult() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMongoClient_consistencyCollections(t *testing.T) {
+	c := offlineClient(t, "payments")
+	col, _ := c.collection("payments")
+
+	// collections are only cloned when options are set
+	if got, err := c.readCollection("payments", db.CreateOptions().SetLimit(1)); err != nil || got != col {
+		t.Errorf("MongoClient.readCollection() = %v, %v, want the cached collection", got, err)
+	}
+	if got, err := c.writeCollection("payments", nil); err != nil || got != col {
+		t.Errorf("MongoClient.writeCollection() = %v, %v, want the cached collection", got, err)
+	}
+
+	read, err := c.readCollection("payments", db.CreateOptions().SetReadPreference(db.ReadSecondary))
+	if err != nil || read == col || read.Name() != "payments" {
+		t.Errorf("MongoClient.readCollection() = %v, %v, want a clone", read, err)
+	}
+	write, err := c.writeCollection("payments", []*db.WriteOptions{db.CreateWriteOptions().SetMajority()})
+	if err != nil || write == col || write.Name() != "payments" {
+		t.Errorf("MongoClient.writeCollection() = %v, %v, want a clone", write, err)
+	}
+
+	if _, err := c.readCollection("payments", db.CreateOptions().SetReadConcern("strong")); !errors.Is(err, db.ErrInvalidOptions) {
+		t.Errorf("MongoClient.readCollection() error = %v, want db.ErrInvalidOptions", err)
+	}
+	if _, err := c.writeCollection("payments", []*db.WriteOptions{db.CreateWriteOptions().SetWriteConcern("-1")}); !errors.Is(err, db.ErrInvalidOptions) {
+		t.Errorf("MongoClient.writeCollection() error = %v, want db.ErrInvalidOptions", err)
+	}
+}
+
+func Test_describeTopology(t *testing.T) {
+	tests := []struct {
+		name        string
+		res         isMasterResult
+		wantTop     string
+		wantServers []string
+	}{
+		{"standalone", isMasterResult{Me: "localhost:27017"}, "standalone", []string{"localhost:27017"}},
+		{"standalone_unknown", isMasterResult{}, "standalone", nil},
+		{"replica_set", isMasterResult{SetName: "rs0", Hosts: []string{"a:27017", "b:27017"}}, "replica set rs0", []string{"a:27017", "b:27017"}},
+		{"sharded", isMasterResult{Msg: "isdbgrid"}, "sharded", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top, servers := describeTopology(tt.res)
+			if top != tt.wantTop || !reflect.DeepEqual(servers, tt.wantServers) {
+				t.Errorf("describeTopology() = %v, %v, want %v, %v", top, servers, tt.wantTop, tt.wantServers)
+			}
+		})
+	}
+}
+
+func TestMongoClient_Health_Disconnected(t *testing.T) {
+	c := offlineClient(t)
+	if h := c.Health(context.Background()); h.Healthy || h.Err == nil {
+		t.Errorf("MongoClient.Health() = %+v, want unhealthy", h)
+	}
+}
+
+func TestMongoClient_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	// nothing listens on port 1, construction must not connect
+	opts := options.Client().ApplyURI("mongodb://127.0.0.1:1").SetServerSelectionTimeout(50 * time.Millisecond)
+	c, err := NewMongoClient("test", []string{"users"}, opts, nil)
+	if err != nil {
+		t.Fatal("NewMongoClient() error:", err)
+	}
+
+	var users []interface{}
+	if err := c.FindAll("users", &users, nil, nil); !errors.Is(err, db.ErrClosed) {
+		t.Errorf("MongoClient.FindAll() before Open error = %v, want db.ErrClosed", err)
+	}
+	if err := c.Insert("users", bson.M{"name": "foo"}); !errors.Is(err, db.ErrClosed) {
+		t.Errorf("MongoClient.Insert() before Open error = %v, want db.ErrClosed", err)
+	}
+	var closed *db.ClosedError
+	if err := c.Ping(ctx); !errors.As(err, &closed) || closed.Database != "test" {
+		t.Errorf("MongoClient.Ping() before Open error = %v, want *db.ClosedError", err)
+	}
+	if err := c.Close(ctx); err != nil {
+		t.Errorf("MongoClient.Close() of a closed client error = %v", err)
+	}
+
+	// the failed connection leaves the client closed and it can be opened again
+	for i := 0; i < 2; i++ {
+		if err := c.Open(ctx); err == nil {
+			t.Fatal("MongoClient.Open() expected error for an unreachable server")
+		}
+		if err := c.Ping(ctx); !errors.Is(err, db.ErrClosed) {
+			t.Errorf("MongoClient.Ping() after failed Open error = %v, want db.ErrClosed", err)
+		}
+	}
+
+	if _, err := NewMongoClient("test", nil, options.Client().ApplyURI("mongodb://localhost/?maxPoolSize=x"), nil); err == nil {
+		t.Errorf("NewMongoClient() expected error for invalid options")
+	}
+}
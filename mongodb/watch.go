@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// changeStream wraps a mongo.ChangeStream to implement db.ChangeStream
+type changeStream struct {
+	stream   *mongo.ChangeStream
+	registry *bsoncodec.Registry
+	event    *db.ChangeEvent
+	err      error
+}
+
+// rawChangeEvent is the part of a mongo change event decoded into db.ChangeEvent
+type rawChangeEvent struct {
+	OperationType string   `bson:"operationType"`
+	DocumentKey   bson.Raw `bson:"documentKey"`
+	FullDocument  bson.Raw `bson:"fullDocument"`
+	Namespace     struct {
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+}
+
+// Watch opens a change stream upon the collection. Updates are streamed with
+// the current version of the document
+func (c *MongoClient) Watch(ctx context.Context, collection string, filter *db.Filter, opts *db.WatchOptions) (db.ChangeStream, error) {
+	col, err := c.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	o := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if opts != nil && opts.ResumeAfter != nil {
+		o.SetResumeAfter(opts.ResumeAfter)
+	}
+	stream, err := col.Watch(ctx, db.ConvertToWatchPipeline(filter), o)
+	if err != nil {
+		return nil, err
+	}
+	return &changeStream{stream: stream, registry: c.registry}, nil
+}
+
+func (s *changeStream) Next(ctx context.Context) bool {
+	if !s.stream.Next(ctx) {
+		s.err = s.stream.Err()
+		if s.err == nil {
+			s.err = ctx.Err()
+		}
+		return false
+	}
+	var raw rawChangeEvent
+	if err := s.stream.Decode(&raw); err != nil {
+		s.err = err
+		return false
+	}
+	s.event = &db.ChangeEvent{
+		OperationType: db.OperationType(raw.OperationType),
+		Collection:    raw.Namespace.Collection,
+		DocumentKey:   raw.DocumentKey,
+		FullDocument:  raw.FullDocument,
+		ResumeToken:   s.stream.ResumeToken(),
+		Registry:      s.registry,
+	}
+	return true
+}
+
+func (s *changeStream) Event() *db.ChangeEvent {
+	return s.event
+}
+
+func (s *changeStream) Err() error {
+	return s.err
+}
+
+func (s *changeStream) Close(ctx context.Context) error {
+	return s.stream.Close(ctx)
+}
@@ -0,0 +1,336 @@
+// Package retry wraps a db.Database, such as mongodb.MongoClient or mock.DB,
+// retrying the operations which fail with transient errors
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// idempotent are the methods which can be repeated without changing the
+// result. Insert and Delete are not: a retried insert can be stored twice
+// and a retried delete can remove a second matching document
+var idempotent = map[string]bool{
+	"Open":              true,
+	"FindOne":           true,
+	"FindAll":           true,
+	"Update":            true,
+	"Upsert":            true,
+	"Search":            true,
+	"SearchWithOptions": true,
+	"Watch":             true,
+}
+
+// Policy sets which operations are retried and how long to wait between attempts
+type Policy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+	jitter         float64
+	retryable      func(error) bool
+	// allowed are the non idempotent methods which are retried, all when allowAll
+	allowed  map[string]bool
+	allowAll bool
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+// CreatePolicy creates a policy making up to 3 attempts, waiting 100ms before
+// the first retry and doubling the wait up to 2s, with a jitter of 20%
+func CreatePolicy() *Policy {
+	return &Policy{
+		maxAttempts:    3,
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     2 * time.Second,
+		multiplier:     2,
+		jitter:         0.2,
+		retryable:      IsRetryable,
+		allowed:        make(map[string]bool),
+		rand:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetMaxAttempts sets the number of attempts including the first one
+func (p *Policy) SetMaxAttempts(n int) *Policy {
+	if n < 1 {
+		n = 1
+	}
+	p.maxAttempts = n
+	return p
+}
+
+// SetBackoff sets the wait before the first retry and the longest wait
+func (p *Policy) SetBackoff(initial, max time.Duration) *Policy {
+	p.initialBackoff = initial
+	p.maxBackoff = max
+	return p
+}
+
+// SetMultiplier sets the factor the wait grows by after every retry
+func (p *Policy) SetMultiplier(m float64) *Policy {
+	p.multiplier = m
+	return p
+}
+
+// SetJitter randomizes every wait by up to the fraction of it in either
+// direction, so clients failing together do not retry together
+func (p *Policy) SetJitter(fraction float64) *Policy {
+	p.jitter = math.Max(0, math.Min(fraction, 1))
+	return p
+}
+
+// SetRetryable sets the function classifying errors as retryable, IsRetryable by default
+func (p *Policy) SetRetryable(retryable func(error) bool) *Policy {
+	p.retryable = retryable
+	return p
+}
+
+// AllowNonIdempotent retries the methods, such as "Insert" when documents
+// have an _id so a repeated insert fails as a duplicate, or every method when
+// called without any
+func (p *Policy) AllowNonIdempotent(methods ...string) *Policy {
+	if len(methods) == 0 {
+		p.allowAll = true
+	}
+	for _, method := range methods {
+		p.allowed[method] = true
+	}
+	return p
+}
+
+// maxDuration is the longest wait, backoffs growing past it are clamped to it
+const maxDuration = time.Duration(math.MaxInt64)
+
+// Backoff returns the wait after the attempt failed, counting from 1
+func (p *Policy) Backoff(attempt int) time.Duration {
+	d := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if max := float64(p.maxBackoff); p.maxBackoff > 0 && d > max {
+		d = max
+	}
+	if p.jitter > 0 {
+		p.randMu.Lock()
+		r := p.rand.Float64()
+		p.randMu.Unlock()
+		d *= 1 + p.jitter*(2*r-1)
+	}
+	// without a max backoff the exponent overflows to +Inf, or NaN when the
+	// initial backoff is 0, neither of which converts to a duration
+	switch {
+	case math.IsNaN(d) || d <= 0:
+		return 0
+	case d >= float64(maxDuration):
+		return maxDuration
+	}
+	return time.Duration(d)
+}
+
+func (p *Policy) retries(method string) bool {
+	return idempotent[method] || p.allowAll || p.allowed[method]
+}
+
+// retryable codes of MongoDB server errors
+var retryableCodes = map[int]bool{
+	6:     true, // HostUnreachable
+	7:     true, // HostNotFound
+	89:    true, // NetworkTimeout
+	91:    true, // ShutdownInProgress
+	112:   true, // WriteConflict
+	189:   true, // PrimarySteppedDown
+	262:   true, // ExceededTimeLimit
+	9001:  true, // SocketException
+	10107: true, // NotMaster
+	11600: true, // InterruptedAtShutdown
+	11602: true, // InterruptedDueToReplStateChange
+	13435: true, // NotMasterNoSlaveOk
+	13436: true, // NotMasterOrSecondary
+}
+
+// retryable labels of MongoDB errors
+var retryableLabels = []string{"NetworkError", "RetryableWriteError", "TransientTransactionError"}
+
+// IsRetryable reports whether the error is transient: db.ErrNetwork, db.ErrTimeout,
+// network timeouts and MongoDB errors with a retryable code or label such as
+// primary stepdowns and write conflicts. Canceled and expired contexts are not retryable
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, db.ErrNetwork) || errors.Is(err, db.ErrTimeout) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var labeled interface{ HasErrorLabel(string) bool }
+	if errors.As(err, &labeled) {
+		for _, label := range retryableLabels {
+			if labeled.HasErrorLabel(label) {
+				return true
+			}
+		}
+	}
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && retryableCodes[int(cmdErr.Code)] {
+		return true
+	}
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		if writeErr.WriteConcernError != nil && retryableCodes[writeErr.WriteConcernError.Code] {
+			return true
+		}
+		for _, e := range writeErr.WriteErrors {
+			if retryableCodes[e.Code] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Database retries the operations of the wrapped database which fail with
+// a retryable error, Close, Ping and Health are never retried
+type Database struct {
+	db.Database
+	policy *Policy
+	ctx    context.Context
+}
+
+// Wrap retries the operations of the database with the policy, CreatePolicy when nil
+func Wrap(database db.Database, policy *Policy) *Database {
+	if policy == nil {
+		policy = CreatePolicy()
+	}
+	return &Database{Database: database, policy: policy, ctx: context.Background()}
+}
+
+// WithContext returns a copy of the database whose operations taking no
+// context stop retrying once ctx is done or its deadline would pass while waiting
+func (d *Database) WithContext(ctx context.Context) *Database {
+	c := *d
+	c.ctx = ctx
+	return &c
+}
+
+func (d *Database) do(ctx context.Context, method string, op func() error) error {
+	return d.policy.do(ctx, method, op)
+}
+
+// Middleware retries the operations passing through db.Wrap with the policy,
+// CreatePolicy when nil, waiting no longer than the context of the operation allows
+func Middleware(policy *Policy) db.Middleware {
+	if policy == nil {
+		policy = CreatePolicy()
+	}
+	return func(next db.Handler) db.Handler {
+		return func(op *db.Operation) error {
+			return policy.do(op.Context, op.Method, func() error {
+				return next(op)
+			})
+		}
+	}
+}
+
+// do calls op until it succeeds, fails with an error which is not retryable,
+// runs out of attempts or the context is done
+func (p *Policy) do(ctx context.Context, method string, op func() error) error {
+	if !p.retries(method) {
+		return op()
+	}
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !p.retryable(err) {
+			return err
+		}
+		if attempt >= p.maxAttempts {
+			return fmt.Errorf("retry: %v() error after %d attempts: %w", method, attempt, err)
+		}
+
+		wait := p.Backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return fmt.Errorf("retry: %v() error after %d attempts, deadline is before the next: %w", method, attempt, err)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry: %v() error after %d attempts, %v: %w", method, attempt, ctx.Err(), err)
+		}
+	}
+}
+
+func (d *Database) Open(ctx context.Context) error {
+	return d.do(ctx, "Open", func() error {
+		return d.Database.Open(ctx)
+	})
+}
+
+func (d *Database) Insert(collection string, object interface{}, opts ...*db.WriteOptions) error {
+	return d.do(d.ctx, "Insert", func() error {
+		return d.Database.Insert(collection, object, opts...)
+	})
+}
+
+func (d *Database) FindOne(collection string, object interface{}, filter *db.Filter, opts *db.Options) error {
+	return d.do(d.ctx, "FindOne", func() error {
+		return d.Database.FindOne(collection, object, filter, opts)
+	})
+}
+
+func (d *Database) FindAll(collection string, slice interface{}, filter *db.Filter, opts *db.Options) error {
+	return d.do(d.ctx, "FindAll", func() error {
+		return d.Database.FindAll(collection, slice, filter, opts)
+	})
+}
+
+func (d *Database) Update(collection string, object interface{}, filter *db.Filter, opts ...*db.WriteOptions) error {
+	return d.do(d.ctx, "Update", func() error {
+		return d.Database.Update(collection, object, filter, opts...)
+	})
+}
+
+func (d *Database) Upsert(collection string, object interface{}, filter *db.Filter, opts ...*db.WriteOptions) error {
+	return d.do(d.ctx, "Upsert", func() error {
+		return d.Database.Upsert(collection, object, filter, opts...)
+	})
+}
+
+func (d *Database) Delete(collection string, filter *db.Filter, opts ...*db.WriteOptions) error {
+	return d.do(d.ctx, "Delete", func() error {
+		return d.Database.Delete(collection, filter, opts...)
+	})
+}
+
+func (d *Database) Search(collection, search string, fields []string, slice interface{}) error {
+	return d.do(d.ctx, "Search", func() error {
+		return d.Database.Search(collection, search, fields, slice)
+	})
+}
+
+func (d *Database) SearchWithOptions(collection, search string, fields []string, slice interface{}, filter *db.Filter, opts *db.Options) error {
+	return d.do(d.ctx, "SearchWithOptions", func() error {
+		return d.Database.SearchWithOptions(collection, search, fields, slice, filter, opts)
+	})
+}
+
+func (d *Database) Watch(ctx context.Context, collection string, filter *db.Filter, opts *db.WatchOptions) (db.ChangeStream, error) {
+	var stream db.ChangeStream
+	err := d.do(ctx, "Watch", func() (err error) {
+		stream, err = d.Database.Watch(ctx, collection, filter, opts)
+		return err
+	})
+	return stream, err
+}
@@ -0,0 +1,269 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sschwartz96/stockpile/db"
+	"github.com/sschwartz96/stockpile/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type item struct {
+	ID   string `bson:"_id"`
+	Name string
+}
+
+// timeoutError is a net.Error which timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"network", fmt.Errorf("wrapped: %w", db.ErrNetwork), true},
+		{"timeout", db.ErrTimeout, true},
+		{"net_timeout", timeoutError{}, true},
+		{"not_found", db.ErrNotFound, false},
+		{"duplicate_key", db.ErrDuplicateKey, false},
+		{"canceled", context.Canceled, false},
+		{"deadline", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), false},
+		{"stepdown", mongo.CommandError{Code: 189, Name: "PrimarySteppedDown"}, true},
+		{"not_master", mongo.CommandError{Code: 10107}, true},
+		{"label", mongo.CommandError{Code: 1, Labels: []string{"TransientTransactionError"}}, true},
+		{"command", mongo.CommandError{Code: 2, Name: "BadValue"}, false},
+		{"write_conflict", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 112}}}, true},
+		{"write_concern", mongo.WriteException{WriteConcernError: &mongo.WriteConcernError{Code: 91}}, true},
+		{"write_duplicate", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, false},
+		{"write_label", mongo.WriteException{Labels: []string{"RetryableWriteError"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := CreatePolicy().SetBackoff(10*time.Millisecond, 50*time.Millisecond).SetJitter(0)
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := p.Backoff(attempt + 1); got != want*time.Millisecond {
+			t.Errorf("Policy.Backoff(%d) = %v, want %v", attempt+1, got, want*time.Millisecond)
+		}
+	}
+
+	p.SetJitter(0.5)
+	for i := 0; i < 100; i++ {
+		if got := p.Backoff(2); got < 10*time.Millisecond || got > 30*time.Millisecond {
+			t.Fatalf("Policy.Backoff(2) = %v, want 20ms ± 50%%", got)
+		}
+	}
+
+	// without a max backoff large attempts are clamped rather than overflowing
+	unbounded := CreatePolicy().SetBackoff(time.Second, 0).SetJitter(0.5)
+	for _, attempt := range []int{100, 2000, math.MaxInt32} {
+		if got := unbounded.Backoff(attempt); got != time.Duration(math.MaxInt64) {
+			t.Errorf("Policy.Backoff(%d) without max = %v, want the longest duration", attempt, got)
+		}
+	}
+	if got := CreatePolicy().SetBackoff(0, 0).Backoff(2000); got != 0 {
+		t.Errorf("Policy.Backoff(2000) without backoff = %v, want 0", got)
+	}
+}
+
+// fastPolicy retries without waiting long
+func fastPolicy() *Policy {
+	return CreatePolicy().SetBackoff(time.Millisecond, time.Millisecond)
+}
+
+func TestDatabase_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    *Policy
+		fault     func(m *mock.DB)
+		call      func(d *Database) error
+		method    string
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			"transient_read",
+			fastPolicy(),
+			func(m *mock.DB) { m.Fail().FindAll("items").Times(2).Return(db.ErrNetwork) },
+			func(d *Database) error {
+				var items []item
+				return d.FindAll("items", &items, nil, nil)
+			},
+			"FindAll", 3, nil,
+		},
+		{
+			"attempts_exhausted",
+			fastPolicy().SetMaxAttempts(4),
+			func(m *mock.DB) { m.Fail().Update("items").Return(db.ErrTimeout) },
+			func(d *Database) error {
+				return d.Update("items", item{ID: "1", Name: "bar"}, &db.Filter{"_id": "1"})
+			},
+			"Update", 4, db.ErrTimeout,
+		},
+		{
+			"not_retryable",
+			fastPolicy(),
+			func(m *mock.DB) {},
+			func(d *Database) error {
+				return d.Update("items", item{ID: "9"}, &db.Filter{"_id": "9"})
+			},
+			"Update", 1, db.ErrNotFound,
+		},
+		{
+			"insert_not_idempotent",
+			fastPolicy(),
+			func(m *mock.DB) { m.Fail().Insert("items").Times(1).Return(db.ErrNetwork) },
+			func(d *Database) error { return d.Insert("items", item{ID: "2"}) },
+			"Insert", 1, db.ErrNetwork,
+		},
+		{
+			"insert_allowed",
+			fastPolicy().AllowNonIdempotent("Insert"),
+			func(m *mock.DB) { m.Fail().Insert("items").Times(1).Return(db.ErrNetwork) },
+			func(d *Database) error { return d.Insert("items", item{ID: "2"}) },
+			"Insert", 2, nil,
+		},
+		{
+			"delete_allowed_all",
+			fastPolicy().AllowNonIdempotent(),
+			func(m *mock.DB) { m.Fail().Delete("items").Times(1).Return(db.ErrNetwork) },
+			func(d *Database) error { return d.Delete("items", &db.Filter{"_id": "1"}) },
+			"Delete", 2, nil,
+		},
+		{
+			"custom_classifier",
+			fastPolicy().SetRetryable(func(err error) bool { return errors.Is(err, db.ErrNotFound) }),
+			func(m *mock.DB) {},
+			func(d *Database) error {
+				return d.Update("items", item{ID: "9"}, &db.Filter{"_id": "9"})
+			},
+			"Update", 3, db.ErrNotFound,
+		},
+		{
+			"watch",
+			fastPolicy(),
+			func(m *mock.DB) { m.Fail().Watch("items").Times(1).Return(db.ErrNetwork) },
+			func(d *Database) error {
+				s, err := d.Watch(context.Background(), "items", nil, nil)
+				if err == nil {
+					err = s.Close(context.Background())
+				}
+				return err
+			},
+			"Watch", 2, nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mock.CreateDB()
+			if err := m.Insert("items", item{ID: "1", Name: "foo"}); err != nil {
+				t.Fatal(err)
+			}
+			m.ResetCalls()
+			tt.fault(m)
+
+			var d db.Database = Wrap(m, tt.policy)
+			err := tt.call(d.(*Database))
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			calls := 0
+			for _, c := range m.Calls() {
+				if c.Method == tt.method {
+					calls++
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("%v called %d time(s), want %d", tt.method, calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDatabase_Context(t *testing.T) {
+	m := mock.CreateDB()
+	m.Fail().FindOne("items").Return(db.ErrNetwork)
+	var found item
+
+	// the deadline passes before the next attempt
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d := Wrap(m, CreatePolicy().SetMaxAttempts(10).SetBackoff(time.Second, time.Second)).WithContext(ctx)
+	start := time.Now()
+	if err := d.FindOne("items", &found, nil, nil); !errors.Is(err, db.ErrNetwork) {
+		t.Errorf("FindOne() error = %v, want db.ErrNetwork", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("FindOne() waited %v for an attempt after the deadline", elapsed)
+	}
+
+	// canceling stops the wait
+	ctx, cancel = context.WithCancel(context.Background())
+	d = Wrap(m, CreatePolicy().SetMaxAttempts(10).SetBackoff(time.Second, time.Second)).WithContext(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start = time.Now()
+	if err := d.FindOne("items", &found, nil, nil); !errors.Is(err, db.ErrNetwork) {
+		t.Errorf("FindOne() error = %v, want db.ErrNetwork", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("FindOne() kept waiting %v after the context was canceled", elapsed)
+	}
+
+	// methods taking a context use it
+	m.Fail().Ping().Return(db.ErrNetwork)
+	if err := Wrap(m, fastPolicy()).Ping(context.Background()); !errors.Is(err, db.ErrNetwork) {
+		t.Errorf("Ping() error = %v, want db.ErrNetwork", err)
+	}
+	m.Expect().Ping().Times(1)
+	m.Verify(t)
+}
+
+func TestMiddleware(t *testing.T) {
+	m := mock.CreateDB()
+	if err := m.Insert("items", item{ID: "1", Name: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	m.Fail().FindAll("items").Times(2).Return(db.ErrNetwork)
+	m.Fail().Delete("items").Times(1).Return(db.ErrNetwork)
+
+	attempts := 0
+	count := func(next db.Handler) db.Handler {
+		return func(op *db.Operation) error {
+			attempts++
+			return next(op)
+		}
+	}
+	d := db.Wrap(m, Middleware(fastPolicy()), count)
+
+	var found []item
+	if err := d.FindAll("items", &found, nil, nil); err != nil {
+		t.Errorf("FindAll() error = %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("FindAll() attempts = %d, want 3", attempts)
+	}
+	if err := d.Delete("items", nil); !errors.Is(err, db.ErrNetwork) || attempts != 4 {
+		t.Errorf("Delete() error = %v after %d attempts, want db.ErrNetwork without retrying", err, attempts-3)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/sschwartz96/stockpile/tracing/oteltrace
+
+go 1.15
+
+require (
+	github.com/sschwartz96/stockpile v0.0.0-20261018202533-fac36a826f4e
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/sdk v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+)
@@ -0,0 +1,73 @@
+// Package oteltrace adapts an OpenTelemetry tracer to tracing.Tracer
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sschwartz96/stockpile/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracer created by Provider
+const InstrumentationName = "github.com/sschwartz96/stockpile"
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// Tracer starts the spans of the operations with t as client spans
+func Tracer(t trace.Tracer) tracing.Tracer {
+	return &tracer{tracer: t}
+}
+
+// Provider starts the spans of the operations with the tracer of the provider,
+// such as otel.GetTracerProvider()
+func Provider(tp trace.TracerProvider) tracing.Tracer {
+	return Tracer(tp.Tracer(InstrumentationName))
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attrs ...tracing.Attribute) {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, a := range attrs {
+		kvs[i] = keyValue(a)
+	}
+	s.span.SetAttributes(kvs...)
+}
+
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
+
+func keyValue(a tracing.Attribute) attribute.KeyValue {
+	key := attribute.Key(a.Key)
+	switch v := a.Value.(type) {
+	case string:
+		return key.String(v)
+	case int:
+		return key.Int(v)
+	case int64:
+		return key.Int64(v)
+	case bool:
+		return key.Bool(v)
+	case float64:
+		return key.Float64(v)
+	}
+	return key.String(fmt.Sprint(a.Value))
+}
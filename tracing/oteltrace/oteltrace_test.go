@@ -0,0 +1,103 @@
+package oteltrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sschwartz96/stockpile/db"
+	"github.com/sschwartz96/stockpile/mock"
+	"github.com/sschwartz96/stockpile/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type item struct {
+	ID   string `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+func TestProvider(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	m := mock.CreateDB()
+	if err := m.Insert("items", item{ID: "1", Name: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	m.Fail().Delete("items").Return(db.ErrNetwork)
+	d := tracing.Wrap(m, Provider(tp), tracing.CreateOptions().SetDatabaseName("app"))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	var found []item
+	if err := db.WithContext(d, ctx).FindAll("items", &found, &db.Filter{"name": "foo"}, nil); err != nil {
+		t.Fatal("FindAll() error:", err)
+	}
+	if err := d.Delete("items", nil); !errors.Is(err, db.ErrNetwork) {
+		t.Errorf("Delete() error = %v, want db.ErrNetwork", err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(spans))
+	}
+	find, del := spans[0], spans[1]
+
+	if find.Name != "FindAll items" || find.SpanKind != trace.SpanKindClient {
+		t.Errorf("span = %v %v, want the FindAll client span", find.Name, find.SpanKind)
+	}
+	if find.Parent.SpanID() != parent.SpanContext().SpanID() || find.SpanContext.TraceID() != parent.SpanContext().TraceID() {
+		t.Error("FindAll span is not a child of the span of the context")
+	}
+	want := map[attribute.Key]attribute.Value{
+		tracing.AttrSystem:     attribute.StringValue("mongodb"),
+		tracing.AttrName:       attribute.StringValue("app"),
+		tracing.AttrOperation:  attribute.StringValue("FindAll"),
+		tracing.AttrCollection: attribute.StringValue("items"),
+		tracing.AttrStatement:  attribute.StringValue(`{"name":"?"}`),
+		tracing.AttrCount:      attribute.IntValue(1),
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range find.Attributes {
+		got[kv.Key] = kv.Value
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("attribute %v = %v, want %v", k, got[k].Emit(), v.Emit())
+		}
+	}
+	if find.Status.Code != codes.Unset {
+		t.Errorf("FindAll status = %v, want unset", find.Status.Code)
+	}
+
+	if del.Parent.IsValid() {
+		t.Error("Delete span has a parent without one in its context")
+	}
+	if del.Status.Code != codes.Error || len(del.Events) != 1 || del.Events[0].Name != "exception" {
+		t.Errorf("Delete status = %v, events = %v, want the error recorded", del.Status, del.Events)
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  attribute.Value
+	}{
+		{"a", attribute.StringValue("a")},
+		{2, attribute.IntValue(2)},
+		{int64(3), attribute.Int64Value(3)},
+		{true, attribute.BoolValue(true)},
+		{1.5, attribute.Float64Value(1.5)},
+		{[]string{"a"}, attribute.StringValue("[a]")},
+	}
+	for _, tt := range tests {
+		if got := keyValue(tracing.Attribute{Key: "k", Value: tt.value}); got.Key != "k" || got.Value != tt.want {
+			t.Errorf("keyValue(%v) = %v, want %v", tt.value, got.Value.Emit(), tt.want.Emit())
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package tracing wraps a db.Database, such as mongodb.MongoClient or mock.DB,
+// creating a span for every operation. Spans are created by a Tracer, such as
+// the OpenTelemetry adapter of the tracing/oteltrace module
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/sschwartz96/stockpile/db"
+)
+
+// Keys of the span attributes, following the OpenTelemetry database conventions
+const (
+	AttrSystem     = "db.system"
+	AttrName       = "db.name"
+	AttrCollection = "db.mongodb.collection"
+	AttrOperation  = "db.operation"
+	AttrStatement  = "db.statement"
+	// AttrCount is the number of documents found, inserted or upserted, left
+	// out when the database does not know it
+	AttrCount = "db.stockpile.count"
+)
+
+// Attribute is a key and a value of a span, values are strings, ints or bools
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer starts spans, the span of ctx is the parent of the new span
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation being traced
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError records the error and marks the span failed
+	RecordError(err error)
+	End()
+}
+
+// Options set the attributes of the spans
+type Options struct {
+	system   string
+	name     string
+	redactor *db.Redactor
+}
+
+// CreateOptions creates options for a "mongodb" system, with every value of
+// the statements redacted
+func CreateOptions() *Options {
+	return &Options{
+		system:   "mongodb",
+		redactor: db.CreateRedactor().SetRedactValues(true),
+	}
+}
+
+// SetSystem sets the db.system attribute
+func (o *Options) SetSystem(system string) *Options {
+	o.system = system
+	return o
+}
+
+// SetDatabaseName sets the db.name attribute
+func (o *Options) SetDatabaseName(name string) *Options {
+	o.name = name
+	return o
+}
+
+// SetRedactor sets the redactor applied to the filters of the statements,
+// nil leaves out the statements
+func (o *Options) SetRedactor(r *db.Redactor) *Options {
+	o.redactor = r
+	return o
+}
+
+// statement returns the filter and search of the operation as redacted JSON,
+// empty when it has neither
+func (o *Options) statement(op *db.Operation) string {
+	if o.redactor == nil || (op.Filter == nil && op.Search == "") {
+		return ""
+	}
+	filter := op.Filter
+	if op.Search != "" {
+		search := db.Filter(db.ConvertToSearchFilter(op.Search, op.Filter))
+		filter = &search
+	}
+	b, err := json.Marshal(o.redactor.Filter(filter))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// SpanName returns the name of the span of the operation, such as "FindAll users"
+func SpanName(op *db.Operation) string {
+	if op.Collection == "" {
+		return op.Method
+	}
+	return op.Method + " " + op.Collection
+}
+
+// Middleware traces the operations passing through db.Wrap with the tracer
+// and the options, CreateOptions when nil. The span of the operation is the
+// child of the span of its context, use db.WithContext for the methods
+// taking no context
+func Middleware(tracer Tracer, opts *Options) db.Middleware {
+	if opts == nil {
+		opts = CreateOptions()
+	}
+	return func(next db.Handler) db.Handler {
+		return func(op *db.Operation) error {
+			ctx, span := tracer.Start(op.Context, SpanName(op))
+			defer span.End()
+
+			attrs := []Attribute{{AttrSystem, opts.system}, {AttrOperation, op.Method}}
+			if opts.name != "" {
+				attrs = append(attrs, Attribute{AttrName, opts.name})
+			}
+			if op.Collection != "" {
+				attrs = append(attrs, Attribute{AttrCollection, op.Collection})
+			}
+			if statement := opts.statement(op); statement != "" {
+				attrs = append(attrs, Attribute{AttrStatement, statement})
+			}
+			span.SetAttributes(attrs...)
+
+			op.Context = ctx
+			err := next(op)
+			// db.ErrNotFound is a result rather than a failure
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				span.RecordError(err)
+				return err
+			}
+			if op.HasCount {
+				span.SetAttributes(Attribute{AttrCount, op.Count})
+			}
+			return err
+		}
+	}
+}
+
+// Wrap traces the operations of the database with the tracer and the
+// options, CreateOptions when nil
+func Wrap(database db.Database, tracer Tracer, opts *Options) db.Database {
+	return db.Wrap(database, Middleware(tracer, opts))
+}
@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sschwartz96/stockpile/db"
+	"github.com/sschwartz96/stockpile/mock"
+)
+
+type item struct {
+	ID   string `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+type parentKey struct{}
+
+// recordingTracer keeps the spans it started
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	name   string
+	parent interface{}
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &recordingSpan{name: name, parent: ctx.Value(parentKey{}), attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, parentKey{}, name), s
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) { s.err = err }
+func (s *recordingSpan) End()                  { s.ended = true }
+
+func TestMiddleware(t *testing.T) {
+	m := mock.CreateDB()
+	if err := m.Insert("items", item{ID: "1", Name: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	m.Fail().Delete("items").Return(db.ErrNetwork)
+
+	tests := []struct {
+		name string
+		opts *Options
+		call func(d db.Database) error
+		want recordingSpan
+		err  error
+	}{
+		{
+			"find",
+			nil,
+			func(d db.Database) error {
+				var found []item
+				return d.FindAll("items", &found, &db.Filter{"_id": "1", "name": db.Filter{"$in": []string{"foo", "bar"}}}, nil)
+			},
+			recordingSpan{name: "FindAll items", attrs: map[string]interface{}{
+				AttrSystem:     "mongodb",
+				AttrOperation:  "FindAll",
+				AttrCollection: "items",
+				AttrStatement:  `{"_id":"?","name":{"$in":["?","?"]}}`,
+				AttrCount:      1,
+			}},
+			nil,
+		},
+		{
+			"search",
+			CreateOptions().SetDatabaseName("app").SetRedactor(db.CreateRedactor()),
+			func(d db.Database) error {
+				var found []item
+				return d.SearchWithOptions("items", "foo", []string{"name"}, &found, &db.Filter{"password": "x"}, nil)
+			},
+			recordingSpan{name: "SearchWithOptions items", attrs: map[string]interface{}{
+				AttrSystem:     "mongodb",
+				AttrName:       "app",
+				AttrOperation:  "SearchWithOptions",
+				AttrCollection: "items",
+				AttrStatement:  `{"$text":{"$search":"foo"},"password":"[REDACTED]"}`,
+				AttrCount:      0,
+			}},
+			nil,
+		},
+		{
+			"error",
+			CreateOptions().SetSystem("mock").SetRedactor(nil),
+			func(d db.Database) error {
+				return d.Delete("items", &db.Filter{"_id": "1"})
+			},
+			recordingSpan{name: "Delete items", err: db.ErrNetwork, attrs: map[string]interface{}{
+				AttrSystem:     "mock",
+				AttrOperation:  "Delete",
+				AttrCollection: "items",
+			}},
+			db.ErrNetwork,
+		},
+		{
+			"not found is not an error",
+			nil,
+			func(d db.Database) error {
+				return d.Update("items", item{ID: "2"}, &db.Filter{"_id": "2"})
+			},
+			recordingSpan{name: "Update items", attrs: map[string]interface{}{
+				AttrSystem:     "mongodb",
+				AttrOperation:  "Update",
+				AttrCollection: "items",
+				AttrStatement:  `{"_id":"?"}`,
+				AttrCount:      0,
+			}},
+			db.ErrNotFound,
+		},
+		{
+			"parent",
+			nil,
+			func(d db.Database) error {
+				ctx := context.WithValue(context.Background(), parentKey{}, "request")
+				return d.Ping(ctx)
+			},
+			recordingSpan{name: "Ping", parent: "request", attrs: map[string]interface{}{
+				AttrSystem:    "mongodb",
+				AttrOperation: "Ping",
+			}},
+			nil,
+		},
+		{
+			"parent of methods without context",
+			nil,
+			func(d db.Database) error {
+				ctx := context.WithValue(context.Background(), parentKey{}, "request")
+				return db.WithContext(d, ctx).Update("items", item{ID: "1", Name: "foo"}, &db.Filter{"_id": "1"})
+			},
+			recordingSpan{name: "Update items", parent: "request", attrs: map[string]interface{}{
+				AttrSystem:     "mongodb",
+				AttrOperation:  "Update",
+				AttrCollection: "items",
+				AttrStatement:  `{"_id":"?"}`,
+			}},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := &recordingTracer{}
+			err := tt.call(Wrap(m, tracer, tt.opts))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+			if len(tracer.spans) != 1 {
+				t.Fatalf("started %d spans, want 1", len(tracer.spans))
+			}
+			got := tracer.spans[0]
+			if !got.ended {
+				t.Error("span was not ended")
+			}
+			if !errors.Is(got.err, tt.want.err) {
+				t.Errorf("span error = %v, want %v", got.err, tt.want.err)
+			}
+			if got.name != tt.want.name || got.parent != tt.want.parent || !reflect.DeepEqual(got.attrs, tt.want.attrs) {
+				t.Errorf("span = %v, want %v", fmt.Sprint(*got), fmt.Sprint(tt.want))
+			}
+		})
+	}
+}
+
+func TestMiddleware_Context(t *testing.T) {
+	// the database and inner middleware receive the context of the span
+	var inner interface{}
+	capture := func(next db.Handler) db.Handler {
+		return func(op *db.Operation) error {
+			inner = op.Context.Value(parentKey{})
+			return next(op)
+		}
+	}
+	d := db.Wrap(mock.CreateDB(), Middleware(&recordingTracer{}, nil), capture)
+	if err := d.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if inner != "Open" {
+		t.Errorf("inner context span = %v, want Open", inner)
+	}
+}